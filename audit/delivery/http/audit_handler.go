@@ -0,0 +1,71 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	domain "spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"strconv"
+	"time"
+)
+
+type AuditHandler struct {
+	AUsecase domain.AuditUsecase
+}
+
+func NewAuditHandler(g *gin.Engine, us domain.AuditUsecase, auth gin.HandlerFunc) {
+	handler := &AuditHandler{
+		AUsecase: us,
+	}
+
+	v1 := g.Group("/api/v1")
+	v1.GET("/audit", auth, handler.GetEntries)
+}
+
+// GetEntries accepts entity_type, entity_id, from, to (RFC 3339) and limit
+// query parameters.
+func (h *AuditHandler) GetEntries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+
+	entries, err := h.AUsecase.GetEntries(ctx, filter)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"result": entries,
+	})
+}
+
+func parseFilter(c *gin.Context) (domain.AuditFilter, error) {
+	var filter domain.AuditFilter
+	var err error
+	filter.EntityType = c.Query("entity_type")
+	if v := c.Query("entity_id"); v != "" {
+		if filter.EntityID, err = strconv.Atoi(v); err != nil {
+			return filter, domain.ErrBadParamInput
+		}
+	}
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, domain.ErrBadParamInput
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, domain.ErrBadParamInput
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, domain.ErrBadParamInput
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/pgerr"
+	"strconv"
+	"strings"
+)
+
+type psqlAuditRepository struct {
+	db     *sqlx.DB
+	schema string
+}
+
+func NewAuditRepository(conn *sqlx.DB, schema string) domain.AuditRepository {
+	return &psqlAuditRepository{conn, pq.QuoteIdentifier(schema)}
+}
+
+// q qualifies the {schema} placeholders in query with the configured schema.
+func (p *psqlAuditRepository) q(query string) string {
+	return strings.ReplaceAll(query, "{schema}", p.schema)
+}
+
+// nullJSON stores empty snapshots as SQL NULL.
+func nullJSON(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+func (p *psqlAuditRepository) AddEntry(ctx context.Context, entry domain.AuditEntry) error {
+	query := p.q(`INSERT INTO {schema}.t_audit (actor, action, entity_type, entity_id, before, after) VALUES ($1, $2, $3, $4, $5, $6)`)
+	_, err := p.db.ExecContext(ctx, query, entry.Actor, entry.Action, entry.EntityType, entry.EntityID, nullJSON(entry.Before), nullJSON(entry.After))
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	return nil
+}
+
+func (p *psqlAuditRepository) GetEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.EntityType != "" {
+		where = append(where, "entity_type = "+arg(filter.EntityType))
+	}
+	if filter.EntityID != 0 {
+		where = append(where, "entity_id = "+arg(filter.EntityID))
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < "+arg(filter.To))
+	}
+	query := p.q(`SELECT id, actor, action, entity_type, entity_id, COALESCE(before, 'null') AS before, COALESCE(after, 'null') AS after, created_at FROM {schema}.t_audit`)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT " + arg(filter.Limit)
+
+	var entries []domain.AuditEntry
+	err := p.db.SelectContext(ctx, &entries, query, args...)
+	if err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"spektr-pages-api/domain"
+	"time"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type AuditUsecase struct {
+	auditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+}
+
+// Record never fails the caller: the change has already been made, so a
+// missing audit row is logged instead. Entries whose snapshot of the entity
+// could not be taken are not written, since they would not show the change.
+func (a AuditUsecase) Record(ctx context.Context, action string, entityType string, entityID int, before interface{}, after interface{}) {
+	actor := "anonymous"
+	if principal, ok := domain.PrincipalFromContext(ctx); ok {
+		actor = principal.Subject
+	}
+	// Detached from the request so a cancelled client still gets audited.
+	ctx, cancel := context.WithTimeout(context.Background(), a.contextTimeout)
+	defer cancel()
+
+	entry := domain.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	err := fillSnapshots(&entry, before, after)
+	if err == nil {
+		err = a.auditRepo.AddEntry(ctx, entry)
+	}
+	if err != nil {
+		log.Printf("audit: %s %s %d: %v", action, entityType, entityID, err)
+	}
+}
+
+// fillSnapshots sets the snapshots of entry, requiring the state after a
+// create, update or restore and the state before a delete.
+func fillSnapshots(entry *domain.AuditEntry, before interface{}, after interface{}) error {
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return err
+	}
+	if entry.Action == domain.AuditDelete && entry.Before == nil {
+		return errors.New("no snapshot before the change")
+	}
+	if entry.Action != domain.AuditDelete && entry.After == nil {
+		return errors.New("no snapshot after the change")
+	}
+	return nil
+}
+
+func snapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func (a AuditUsecase) GetEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.contextTimeout)
+	defer cancel()
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+	entries, err := a.auditRepo.GetEntries(ctx, filter)
+	if err != nil {
+		return []domain.AuditEntry{}, err
+	}
+	return entries, nil
+}
+
+func NewAuditUsecase(repo domain.AuditRepository, timeout time.Duration) domain.AuditUsecase {
+	return &AuditUsecase{
+		auditRepo:      repo,
+		contextTimeout: timeout,
+	}
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"spektr-pages-api/domain"
+	"time"
+)
+
+// CatalogPrefix starts the keys of every cached catalog read. Tariffs,
+// cities, types and icons reference each other, so any catalog write drops
+// the whole prefix rather than working out which entries it touched.
+const CatalogPrefix = "catalog:"
+
+// Remember returns the value cached under key, or calls load and caches its
+// result. Errors are returned as is and never cached.
+func Remember[T any](ctx context.Context, c domain.Cache, key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	if b, ok := c.Get(ctx, key); ok {
+		var v T
+		if err := json.Unmarshal(b, &v); err == nil {
+			return v, nil
+		}
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	if b, err := json.Marshal(v); err == nil {
+		c.Set(ctx, key, b, ttl)
+	}
+	return v, nil
+}
+
+// Key builds a catalog key from a name and the arguments of the read.
+func Key(name string, args ...interface{}) string {
+	b, _ := json.Marshal(args)
+	return CatalogPrefix + name + ":" + string(b)
+}
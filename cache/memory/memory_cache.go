@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"spektr-pages-api/domain"
+	"strings"
+	"time"
+)
+
+type memoryCache struct {
+	lru *expirable.LRU[string, entry]
+}
+
+// entry carries its own expiry since the LRU only has a single TTL for all
+// keys.
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache keeps up to size entries in process, evicting the least
+// recently used ones first.
+func NewMemoryCache(size int) domain.Cache {
+	return &memoryCache{lru: expirable.NewLRU[string, entry](size, nil, 0)}
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	e, ok := m.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		m.lru.Remove(key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	e := entry{value: value}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	m.lru.Add(key, e)
+}
+
+func (m *memoryCache) DeletePrefix(ctx context.Context, prefix string) {
+	for _, key := range m.lru.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			m.lru.Remove(key)
+		}
+	}
+}
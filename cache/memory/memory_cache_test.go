@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	c.Set(ctx, "catalog:cities", []byte("cities"), 0)
+	c.Set(ctx, "catalog:expired", []byte("old"), time.Nanosecond)
+	c.Set(ctx, "other:key", []byte("other"), 0)
+	time.Sleep(time.Millisecond)
+
+	if v, ok := c.Get(ctx, "catalog:cities"); !ok || string(v) != "cities" {
+		t.Errorf("Get(catalog:cities) = %q, %v; want cities", v, ok)
+	}
+	if _, ok := c.Get(ctx, "catalog:expired"); ok {
+		t.Error("Get returned an expired entry")
+	}
+
+	c.DeletePrefix(ctx, "catalog:")
+	if _, ok := c.Get(ctx, "catalog:cities"); ok {
+		t.Error("DeletePrefix kept an entry with the prefix")
+	}
+	if _, ok := c.Get(ctx, "other:key"); !ok {
+		t.Error("DeletePrefix dropped an entry without the prefix")
+	}
+}
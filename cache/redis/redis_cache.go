@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"spektr-pages-api/domain"
+	"time"
+)
+
+type redisCache struct {
+	client *redis.Client
+}
+
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// NewRedisCache connects to Redis so that every API instance shares one
+// cache and sees the same invalidations.
+func NewRedisCache(ctx context.Context, cfg Config) (domain.Cache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return &redisCache{client: client}, nil
+}
+
+func (r *redisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Println("cache:", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	err := r.client.Set(ctx, key, value, ttl).Err()
+	if err != nil {
+		log.Println("cache:", err)
+	}
+}
+
+func (r *redisCache) DeletePrefix(ctx context.Context, prefix string) {
+	iter := r.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Println("cache:", err)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		log.Println("cache:", err)
+	}
+}
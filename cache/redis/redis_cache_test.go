@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRedisCache runs against the server in REDIS_TEST_ADDR, e.g. a local
+// redis-server on "localhost:6379", and is skipped without one. It only
+// touches keys below a prefix of its own.
+func TestRedisCache(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+	ctx := context.Background()
+	c, err := NewRedisCache(ctx, Config{Addr: addr, Password: os.Getenv("REDIS_PASSWORD")})
+	if err != nil {
+		t.Skipf("no Redis at %s: %v", addr, err)
+	}
+	prefix := "test:" + t.Name() + ":"
+	defer c.DeletePrefix(ctx, prefix)
+
+	c.Set(ctx, prefix+"catalog:cities", []byte("cities"), time.Minute)
+	c.Set(ctx, prefix+"other", []byte("other"), time.Minute)
+	if v, ok := c.Get(ctx, prefix+"catalog:cities"); !ok || string(v) != "cities" {
+		t.Errorf("Get = %q, %v; want cities", v, ok)
+	}
+	if _, ok := c.Get(ctx, prefix+"missing"); ok {
+		t.Error("Get found a key that was never set")
+	}
+
+	c.DeletePrefix(ctx, prefix+"catalog:")
+	if _, ok := c.Get(ctx, prefix+"catalog:cities"); ok {
+		t.Error("DeletePrefix kept an entry with the prefix")
+	}
+	if _, ok := c.Get(ctx, prefix+"other"); !ok {
+		t.Error("DeletePrefix dropped an entry without the prefix")
+	}
+}
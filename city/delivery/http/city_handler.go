@@ -2,34 +2,100 @@ package http
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"net/http"
 	domain "spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"spektr-pages-api/pagination"
+	"strconv"
+	"time"
 )
 
 type CityHandler struct {
 	CUsecase domain.CityUsecase
 }
 
-func NewCityHandler(g *gin.Engine, us domain.CityUsecase) {
+func NewCityHandler(g *gin.Engine, us domain.CityUsecase, auth gin.HandlerFunc) {
 	handler := &CityHandler{
 		CUsecase: us,
 	}
 
-	g.GET("/cities", handler.GetCities)
-	g.DELETE("/city", handler.RemoveCity)
-	g.POST("/city", handler.AddCity)
-	g.DELETE("/tariff-city", handler.RemoveCityTariff)
+	v1 := g.Group("/api/v1")
+	v1.GET("/cities", handler.GetCities)
+	v1.GET("/cities/:id", handler.GetCity)
+	v1.POST("/cities", auth, handler.AddCity)
+	v1.PATCH("/cities/:id", auth, handler.UpdateCity)
+	v1.DELETE("/cities/:id", auth, handler.DeleteCity)
+	v1.POST("/cities/:id/tariffs/:tariff_id", auth, handler.LinkCityTariff)
+	v1.DELETE("/cities/:id/tariffs/:tariff_id", auth, handler.DeleteCityTariff)
+	v1.POST("/cities/:id/tariffs", auth, handler.LinkCityTariffs)
+	v1.DELETE("/cities/:id/tariffs", auth, handler.UnlinkCityTariffs)
+	v1.POST("/tariffs/:id/cities", auth, handler.LinkTariffCities)
+	v1.DELETE("/tariffs/:id/cities", auth, handler.UnlinkTariffCities)
+	v1.GET("/trash/cities", auth, handler.GetDeletedCities)
+	v1.POST("/cities/:id/restore", auth, handler.RestoreCity)
+
+	// Routes from before /api/v1, reading ids from JSON bodies. Kept while
+	// clients migrate; disable with server.legacy_routes=false.
+	if !viper.GetBool("server.legacy_routes") {
+		return
+	}
+	g.GET("/cities", handler.GetAllCities)
+	g.GET("/city/:id", handler.GetCity)
+	g.DELETE("/city", auth, handler.RemoveCity)
+	g.POST("/city", auth, handler.AddCity)
+	g.POST("/tariff-city", auth, handler.AddCityTariff)
+	g.DELETE("/tariff-city", auth, handler.RemoveCityTariff)
+	g.PATCH("/city/:id", auth, handler.UpdateCity)
 }
 
 func (h *CityHandler) GetCities(c *gin.Context) {
+	modified := h.lastModified(c)
+	ctx := c.Request.Context()
+
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	cities, err := h.CUsecase.GetCities(ctx, page)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	middleware.ConditionalJSON(c, modified, cities)
+}
+
+// GetAllCities serves the legacy route, which returns every city as a
+// plain array.
+func (h *CityHandler) GetAllCities(c *gin.Context) {
+	modified := h.lastModified(c)
+	ctx := c.Request.Context()
+
+	cities, err := h.CUsecase.GetCities(ctx, domain.PageRequest{Limit: domain.NoPageLimit})
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	middleware.ConditionalJSON(c, modified, cities.Items)
+}
+
+func (h *CityHandler) GetCity(c *gin.Context) {
+	modified := h.lastModified(c)
 	ctx := c.Request.Context()
 
-	cities, err := h.CUsecase.GetCities(ctx)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cities"})
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city id"))
 		return
 	}
-	c.JSON(http.StatusOK, cities)
+
+	city, err := h.CUsecase.GetCity(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	middleware.ConditionalJSON(c, modified, city)
 }
 
 func (h *CityHandler) RemoveCity(c *gin.Context) {
@@ -38,13 +104,40 @@ func (h *CityHandler) RemoveCity(c *gin.Context) {
 	var id domain.City
 	err := c.ShouldBindJSON(&id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid city data"})
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city data"))
+		return
+	}
+
+	cascade, err := cascadeParam(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	err = h.CUsecase.RemoveCity(ctx, id.Id, cascade)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+
+func (h *CityHandler) DeleteCity(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city id"))
 		return
 	}
 
-	err = h.CUsecase.RemoveCity(ctx, id.Id)
+	cascade, err := cascadeParam(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove city"})
+		middleware.RenderError(c, err)
+		return
+	}
+	err = h.CUsecase.RemoveCity(ctx, id, cascade)
+	if err != nil {
+		middleware.RenderError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"result": "ok"})
@@ -55,13 +148,13 @@ func (h *CityHandler) AddCity(c *gin.Context) {
 
 	var city domain.City
 	if err := c.ShouldBindJSON(&city); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid city data"})
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city data"))
 		return
 	}
 
 	err := h.CUsecase.AddCity(ctx, city)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add city"})
+		middleware.RenderError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"result": "ok"})
@@ -71,15 +164,37 @@ func (h *CityHandler) RemoveCityTariff(c *gin.Context) {
 
 	var cityTariff domain.CityTariff
 	if err := c.ShouldBindJSON(&cityTariff); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid JSON data"))
 		return
 	}
 
 	err := h.CUsecase.RemoveCityTariff(ctx, cityTariff)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "City tariff removed successfully",
+	})
+}
+func (h *CityHandler) DeleteCityTariff(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	cityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city id"))
+		return
+	}
+	tariffID, err := strconv.Atoi(c.Param("tariff_id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid tariff id"))
+		return
+	}
+
+	err = h.CUsecase.RemoveCityTariff(ctx, domain.CityTariff{City: cityID, Tariff: tariffID})
+	if err != nil {
+		middleware.RenderError(c, err)
 		return
 	}
 
@@ -87,20 +202,162 @@ func (h *CityHandler) RemoveCityTariff(c *gin.Context) {
 		"message": "City tariff removed successfully",
 	})
 }
-func getStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
-	}
-	switch err {
-	case domain.ErrInternalServerError:
-		return http.StatusInternalServerError
-	case domain.ErrNotFound:
-		return http.StatusNotFound
-	case domain.ErrConflict:
-		return http.StatusConflict
-	case domain.UserAlreadyExist:
-		return http.StatusConflict
-	default:
-		return http.StatusInternalServerError
+func (h *CityHandler) AddCityTariff(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cityTariff domain.CityTariff
+	if err := c.ShouldBindJSON(&cityTariff); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid JSON data"))
+		return
+	}
+
+	err := h.CUsecase.AddCityTariff(ctx, cityTariff)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"result": "ok"})
+}
+func (h *CityHandler) LinkCityTariff(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	cityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city id"))
+		return
+	}
+	tariffID, err := strconv.Atoi(c.Param("tariff_id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid tariff id"))
+		return
+	}
+
+	err = h.CUsecase.AddCityTariff(ctx, domain.CityTariff{City: cityID, Tariff: tariffID})
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"result": "ok"})
+}
+
+// LinkCityTariffs and UnlinkCityTariffs link or unlink many tariffs to the
+// city in the URL; LinkTariffCities and UnlinkTariffCities do the same for
+// many cities of one tariff.
+func (h *CityHandler) LinkCityTariffs(c *gin.Context) {
+	h.changeLinks(c, true, true)
+}
+func (h *CityHandler) UnlinkCityTariffs(c *gin.Context) {
+	h.changeLinks(c, true, false)
+}
+func (h *CityHandler) LinkTariffCities(c *gin.Context) {
+	h.changeLinks(c, false, true)
+}
+func (h *CityHandler) UnlinkTariffCities(c *gin.Context) {
+	h.changeLinks(c, false, false)
+}
+
+func (h *CityHandler) changeLinks(c *gin.Context, byCity bool, add bool) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid id"))
+		return
+	}
+	var body domain.CityTariffLinks
+	if err := c.ShouldBindJSON(&body); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid JSON data"))
+		return
+	}
+	var links []domain.CityTariff
+	if byCity {
+		for _, tariffID := range body.TariffIDs {
+			links = append(links, domain.CityTariff{City: id, Tariff: tariffID})
+		}
+	} else {
+		for _, cityID := range body.CityIDs {
+			links = append(links, domain.CityTariff{City: cityID, Tariff: id})
+		}
+	}
+
+	if add {
+		err = h.CUsecase.AddCityTariffs(ctx, links)
+	} else {
+		err = h.CUsecase.RemoveCityTariffs(ctx, links)
+	}
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (h *CityHandler) UpdateCity(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city id"))
+		return
+	}
+	var patch domain.CityPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city data"))
+		return
+	}
+
+	err = h.CUsecase.UpdateCity(ctx, id, patch)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (h *CityHandler) GetDeletedCities(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	cities, err := h.CUsecase.GetDeletedCities(ctx)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": cities})
+}
+func (h *CityHandler) RestoreCity(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid city id"))
+		return
+	}
+
+	err = h.CUsecase.RestoreCity(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+
+// lastModified is read before the data so Last-Modified never runs ahead of
+// the body. Without it the response just goes out with the ETag alone.
+func (h *CityHandler) lastModified(c *gin.Context) time.Time {
+	modified, err := h.CUsecase.LastModified(c.Request.Context())
+	if err != nil {
+		return time.Time{}
+	}
+	return modified
+}
+
+// cascadeParam reads the cascade query parameter of the delete routes.
+func cascadeParam(c *gin.Context) (bool, error) {
+	v := c.Query("cascade")
+	if v == "" {
+		return false, nil
+	}
+	cascade, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, domain.ErrBadParamInput
 	}
+	return cascade, nil
 }
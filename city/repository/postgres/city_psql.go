@@ -2,54 +2,224 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"spektr-pages-api/domain"
+	"spektr-pages-api/pagination"
+	"spektr-pages-api/pgerr"
+	"strings"
+	"time"
 )
 
 type psqlCityRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	schema string
 }
 
-func NewCityRepository(conn *sqlx.DB) domain.CityRepository {
-	return &psqlCityRepository{conn}
+func NewCityRepository(conn *sqlx.DB, schema string) domain.CityRepository {
+	return &psqlCityRepository{conn, pq.QuoteIdentifier(schema)}
 }
 
-func (p *psqlCityRepository) GetCities(ctx context.Context) ([]domain.City, error) {
+// q qualifies the {schema} placeholders in query with the configured schema.
+func (p *psqlCityRepository) q(query string) string {
+	return strings.ReplaceAll(query, "{schema}", p.schema)
+}
+
+// citySorts maps the sort fields of GetCities to columns.
+var citySorts = map[string]string{"id": "id", "name": "name"}
+
+func (p *psqlCityRepository) GetCities(ctx context.Context, page domain.PageRequest) (domain.Page[domain.City], error) {
+	offset, err := page.Start()
+	if err != nil {
+		return domain.Page[domain.City]{}, err
+	}
+	var total int
+	err = p.db.GetContext(ctx, &total, p.q("SELECT count(*) FROM {schema}.t_city WHERE deleted_at IS NULL"))
+	if err != nil {
+		return domain.Page[domain.City]{}, pgerr.Map(err)
+	}
 	var cities []domain.City
-	err := p.db.SelectContext(ctx, &cities, "SELECT id, name FROM spektr.t_city")
+	query := p.q("SELECT id, name FROM {schema}.t_city WHERE deleted_at IS NULL ORDER BY ") + pagination.OrderBy(citySorts, page) + " LIMIT $1 OFFSET $2"
+	err = p.db.SelectContext(ctx, &cities, query, pagination.LimitArg(page), offset)
 	if err != nil {
-		return nil, domain.ErrInternalServerError
+		return domain.Page[domain.City]{}, pgerr.Map(err)
 	}
-	return cities, nil
+	return domain.NewPage(cities, total, page, offset), nil
 }
 
-func (p *psqlCityRepository) AddCity(ctx context.Context, city domain.City) error {
-	_, err := p.db.ExecContext(ctx, "INSERT INTO spektr.t_city (name) VALUES ($1)", city.Name)
+func (p *psqlCityRepository) GetCity(ctx context.Context, cityID int) (domain.City, error) {
+	var city domain.City
+	err := p.db.GetContext(ctx, &city, p.q("SELECT id, name FROM {schema}.t_city WHERE id = $1 AND deleted_at IS NULL"), cityID)
+	if err == sql.ErrNoRows {
+		return domain.City{}, domain.ErrNotFound
+	}
 	if err != nil {
-		return err
+		return domain.City{}, pgerr.Map(err)
 	}
-	return nil
+	return city, nil
+}
+
+func (p *psqlCityRepository) AddCity(ctx context.Context, city domain.City) (int, error) {
+	var insertedID int
+	err := p.db.QueryRowxContext(ctx, p.q("INSERT INTO {schema}.t_city (name) VALUES ($1) RETURNING id"), city.Name).Scan(&insertedID)
+	if err != nil {
+		return 0, pgerr.Map(err)
+	}
+	return insertedID, nil
 }
 
-func (p *psqlCityRepository) RemoveCity(ctx context.Context, cityID int) error {
-	_, err := p.db.ExecContext(ctx, "DELETE FROM spektr.t_city WHERE id = $1", cityID)
+// RemoveCity moves the city to the trash. Without cascade it fails while
+// tariffs, trashed ones included, are offered in the city; with cascade
+// those links are deleted, so RestoreCity brings the city back without them.
+func (p *psqlCityRepository) RemoveCity(ctx context.Context, cityID int, cascade bool) (domain.Cascade, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	defer tx.Rollback()
+
+	// The row lock holds off new links until the delete commits.
+	err = tx.GetContext(ctx, new(int), p.q("SELECT id FROM {schema}.t_city WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"), cityID)
+	if err != nil {
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	var cascaded domain.Cascade
+	if cascade {
+		err = tx.SelectContext(ctx, &cascaded.CityTariffs, p.q("DELETE FROM {schema}.t_city_tariff WHERE city_id = $1 RETURNING city_id, tariff_id"), cityID)
+		if err != nil {
+			return domain.Cascade{}, pgerr.Map(err)
+		}
+	} else {
+		var dependants []domain.Dependant
+		err = tx.SelectContext(ctx, &dependants, p.q(`
+			SELECT t.id, t.title AS name
+			FROM {schema}.t_city_tariff ct
+			JOIN {schema}.t_tariff t ON t.id = ct.tariff_id
+			WHERE ct.city_id = $1
+			ORDER BY t.id`), cityID)
+		if err != nil {
+			return domain.Cascade{}, pgerr.Map(err)
+		}
+		if len(dependants) > 0 {
+			for i := range dependants {
+				dependants[i].Entity = domain.EntityTariff
+			}
+			return domain.Cascade{}, domain.DependantsError(dependants)
+		}
+	}
+	_, err = tx.ExecContext(ctx, p.q("UPDATE {schema}.t_city SET deleted_at = now() WHERE id = $1"), cityID)
+	if err != nil {
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	return cascaded, nil
+}
+
+func (p *psqlCityRepository) RestoreCity(ctx context.Context, cityID int) error {
+	res, err := p.db.ExecContext(ctx, p.q("UPDATE {schema}.t_city SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL"), cityID)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
 	}
 	return nil
 }
+
+func (p *psqlCityRepository) GetDeletedCities(ctx context.Context) ([]domain.City, error) {
+	var cities []domain.City
+	err := p.db.SelectContext(ctx, &cities, p.q("SELECT id, name, deleted_at FROM {schema}.t_city WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC"))
+	if err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return cities, nil
+}
+
 func (p *psqlCityRepository) RemoveCityTariff(ctx context.Context, cityTariff domain.CityTariff) error {
-	query := `DELETE FROM t_city_tariff WHERE city_id = $1 AND tariff_id = $2`
+	query := p.q(`DELETE FROM {schema}.t_city_tariff WHERE city_id = $1 AND tariff_id = $2`)
 	stmt, err := p.db.PrepareContext(ctx, query)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
 	}
 	defer stmt.Close()
 
 	_, err = stmt.ExecContext(ctx, cityTariff.City, cityTariff.Tariff)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
+	}
+
+	return nil
+}
+
+func (p *psqlCityRepository) AddCityTariff(ctx context.Context, cityTariff domain.CityTariff) error {
+	return p.AddCityTariffs(ctx, []domain.CityTariff{cityTariff})
+}
+
+func (p *psqlCityRepository) AddCityTariffs(ctx context.Context, links []domain.CityTariff) error {
+	cityIDs, tariffIDs := splitLinks(links)
+	query := p.q(`
+		INSERT INTO {schema}.t_city_tariff (city_id, tariff_id)
+		SELECT * FROM unnest($1::int[], $2::int[])
+		ON CONFLICT DO NOTHING`)
+	_, err := p.db.ExecContext(ctx, query, pq.Array(cityIDs), pq.Array(tariffIDs))
+	if err != nil {
+		// A link to a city or tariff that does not exist is ErrBadParamInput.
+		return pgerr.Map(err)
 	}
+	return nil
+}
 
+func (p *psqlCityRepository) RemoveCityTariffs(ctx context.Context, links []domain.CityTariff) error {
+	cityIDs, tariffIDs := splitLinks(links)
+	query := p.q(`
+		DELETE FROM {schema}.t_city_tariff ct
+		USING unnest($1::int[], $2::int[]) AS l(city_id, tariff_id)
+		WHERE ct.city_id = l.city_id AND ct.tariff_id = l.tariff_id`)
+	_, err := p.db.ExecContext(ctx, query, pq.Array(cityIDs), pq.Array(tariffIDs))
+	if err != nil {
+		return pgerr.Map(err)
+	}
 	return nil
 }
+
+// splitLinks turns links into the parallel arrays the bulk queries unnest.
+func splitLinks(links []domain.CityTariff) ([]int64, []int64) {
+	cityIDs := make([]int64, 0, len(links))
+	tariffIDs := make([]int64, 0, len(links))
+	for _, l := range links {
+		cityIDs = append(cityIDs, int64(l.City))
+		tariffIDs = append(tariffIDs, int64(l.Tariff))
+	}
+	return cityIDs, tariffIDs
+}
+
+func (p *psqlCityRepository) UpdateCity(ctx context.Context, cityID int, patch domain.CityPatch) error {
+	res, err := p.db.ExecContext(ctx, p.q("UPDATE {schema}.t_city SET name = COALESCE($2, name) WHERE id = $1 AND deleted_at IS NULL"), cityID, patch.Name)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (p *psqlCityRepository) LastModified(ctx context.Context) (time.Time, error) {
+	var modified time.Time
+	err := p.db.GetContext(ctx, &modified, p.q("SELECT updated_at FROM {schema}.t_table_change WHERE table_name = 't_city'"))
+	if err != nil {
+		return time.Time{}, pgerr.Map(err)
+	}
+	return modified, nil
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"spektr-pages-api/cache"
+	"spektr-pages-api/domain"
+	"time"
+)
+
+// cachedCityUsecase serves city reads from a cache and drops the catalog
+// from it on every write, since tariff reads depend on cities too.
+type cachedCityUsecase struct {
+	domain.CityUsecase
+	cache domain.Cache
+	ttl   time.Duration
+}
+
+func NewCachedCityUsecase(next domain.CityUsecase, c domain.Cache, ttl time.Duration) domain.CityUsecase {
+	return &cachedCityUsecase{
+		CityUsecase: next,
+		cache:       c,
+		ttl:         ttl,
+	}
+}
+
+func (c *cachedCityUsecase) GetCities(ctx context.Context, page domain.PageRequest) (domain.Page[domain.City], error) {
+	return cache.Remember(ctx, c.cache, cache.Key("cities", page), c.ttl, func() (domain.Page[domain.City], error) {
+		return c.CityUsecase.GetCities(ctx, page)
+	})
+}
+
+func (c *cachedCityUsecase) GetCity(ctx context.Context, cityID int) (domain.City, error) {
+	return cache.Remember(ctx, c.cache, cache.Key("city", cityID), c.ttl, func() (domain.City, error) {
+		return c.CityUsecase.GetCity(ctx, cityID)
+	})
+}
+
+// invalidate drops the catalog after a write, whether or not it succeeded;
+// a failed write may still have changed something.
+func (c *cachedCityUsecase) invalidate(ctx context.Context) {
+	c.cache.DeletePrefix(ctx, cache.CatalogPrefix)
+}
+
+func (c *cachedCityUsecase) RemoveCity(ctx context.Context, cityID int, cascade bool) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.RemoveCity(ctx, cityID, cascade)
+}
+
+func (c *cachedCityUsecase) AddCity(ctx context.Context, city domain.City) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.AddCity(ctx, city)
+}
+
+func (c *cachedCityUsecase) RemoveCityTariff(ctx context.Context, cityTariff domain.CityTariff) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.RemoveCityTariff(ctx, cityTariff)
+}
+
+func (c *cachedCityUsecase) UpdateCity(ctx context.Context, cityID int, patch domain.CityPatch) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.UpdateCity(ctx, cityID, patch)
+}
+
+func (c *cachedCityUsecase) RestoreCity(ctx context.Context, cityID int) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.RestoreCity(ctx, cityID)
+}
+
+func (c *cachedCityUsecase) AddCityTariff(ctx context.Context, cityTariff domain.CityTariff) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.AddCityTariff(ctx, cityTariff)
+}
+
+func (c *cachedCityUsecase) AddCityTariffs(ctx context.Context, links []domain.CityTariff) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.AddCityTariffs(ctx, links)
+}
+
+func (c *cachedCityUsecase) RemoveCityTariffs(ctx context.Context, links []domain.CityTariff) error {
+	defer c.invalidate(ctx)
+	return c.CityUsecase.RemoveCityTariffs(ctx, links)
+}
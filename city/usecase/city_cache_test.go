@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+	"spektr-pages-api/cache/memory"
+	"spektr-pages-api/domain"
+	"testing"
+	"time"
+)
+
+// countingCityUsecase counts the reads that reach it. Methods it does not
+// override panic through the nil embedded interface.
+type countingCityUsecase struct {
+	domain.CityUsecase
+	reads int
+}
+
+func (u *countingCityUsecase) GetCities(ctx context.Context, page domain.PageRequest) (domain.Page[domain.City], error) {
+	u.reads++
+	return domain.Page[domain.City]{Items: []domain.City{{Id: 1, Name: "Moscow"}}}, nil
+}
+
+func (u *countingCityUsecase) AddCity(ctx context.Context, city domain.City) error {
+	return nil
+}
+
+func TestCachedCityUsecaseInvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	next := &countingCityUsecase{}
+	u := NewCachedCityUsecase(next, memory.NewMemoryCache(100), time.Minute)
+	page := domain.PageRequest{Limit: 10}
+
+	for i := 0; i < 2; i++ {
+		if _, err := u.GetCities(ctx, page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if next.reads != 1 {
+		t.Fatalf("reads before a write = %d, want 1", next.reads)
+	}
+
+	if err := u.AddCity(ctx, domain.City{Name: "Kazan"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.GetCities(ctx, page); err != nil {
+		t.Fatal(err)
+	}
+	if next.reads != 2 {
+		t.Errorf("reads after a write = %d, want 2", next.reads)
+	}
+}
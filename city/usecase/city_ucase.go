@@ -2,62 +2,208 @@ package usecase
 
 import (
 	"context"
+	"log"
 	"spektr-pages-api/domain"
+	"spektr-pages-api/validation"
 	"time"
 )
 
 type CityUsecase struct {
 	cityRepo       domain.CityRepository
+	audit          domain.AuditUsecase
 	contextTimeout time.Duration
 }
 
-func (c CityUsecase) GetCities(ctx context.Context) ([]domain.City, error) {
+func (c CityUsecase) GetCities(ctx context.Context, page domain.PageRequest) (domain.Page[domain.City], error) {
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	cities, err := c.cityRepo.GetCities(ctx)
+	if err := page.Normalize("id", "name"); err != nil {
+		return domain.Page[domain.City]{}, err
+	}
+	cities, err := c.cityRepo.GetCities(ctx, page)
 	if err != nil {
-		return []domain.City{}, err
+		return domain.Page[domain.City]{}, err
 	}
 	return cities, nil
 }
 
-func (c CityUsecase) AddCity(ctx context.Context, city domain.City) error {
+func (c CityUsecase) GetCity(ctx context.Context, cityID int) (domain.City, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	// Perform any validation or business logic checks on the city data here
+	city, err := c.cityRepo.GetCity(ctx, cityID)
+	if err != nil {
+		return domain.City{}, err
+	}
+	return city, nil
+}
 
-	err := c.cityRepo.AddCity(ctx, city)
+func (c CityUsecase) AddCity(ctx context.Context, city domain.City) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	if err := validation.Struct(city); err != nil {
+		return err
+	}
+	id, err := c.cityRepo.AddCity(ctx, city)
 	if err != nil {
 		return err
 	}
+	city.Id = id
+	c.audit.Record(ctx, domain.AuditCreate, domain.EntityCity, id, nil, city)
 	return nil
 }
 
-func (c CityUsecase) RemoveCity(ctx context.Context, cityID int) error {
+func (c CityUsecase) RemoveCity(ctx context.Context, cityID int, cascade bool) error {
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	err := c.cityRepo.RemoveCity(ctx, cityID)
+	before := c.citySnapshot(ctx, cityID)
+	cascaded, err := c.cityRepo.RemoveCity(ctx, cityID, cascade)
 	if err != nil {
 		return err
 	}
+	for _, l := range cascaded.CityTariffs {
+		c.audit.Record(ctx, domain.AuditDelete, domain.EntityCityTariff, l.Tariff, l, nil)
+	}
+	c.audit.Record(ctx, domain.AuditDelete, domain.EntityCity, cityID, before, nil)
 	return nil
 }
 func (c CityUsecase) RemoveCityTariff(ctx context.Context, cityTariff domain.CityTariff) error {
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
+	if err := validation.Struct(cityTariff); err != nil {
+		return err
+	}
+
 	err := c.cityRepo.RemoveCityTariff(ctx, cityTariff)
 	if err != nil {
 		return err
 	}
+	c.audit.Record(ctx, domain.AuditDelete, domain.EntityCityTariff, cityTariff.Tariff, cityTariff, nil)
+	return nil
+}
+func (c CityUsecase) AddCityTariff(ctx context.Context, cityTariff domain.CityTariff) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	if err := validation.Struct(cityTariff); err != nil {
+		return err
+	}
+
+	err := c.cityRepo.AddCityTariff(ctx, cityTariff)
+	if err != nil {
+		return err
+	}
+	c.audit.Record(ctx, domain.AuditCreate, domain.EntityCityTariff, cityTariff.Tariff, nil, cityTariff)
+	return nil
+}
+func (c CityUsecase) AddCityTariffs(ctx context.Context, links []domain.CityTariff) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	if len(links) == 0 {
+		return domain.ErrBadParamInput
+	}
+	for _, l := range links {
+		if err := validation.Struct(l); err != nil {
+			return err
+		}
+	}
+	err := c.cityRepo.AddCityTariffs(ctx, links)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		c.audit.Record(ctx, domain.AuditCreate, domain.EntityCityTariff, l.Tariff, nil, l)
+	}
+	return nil
+}
+func (c CityUsecase) RemoveCityTariffs(ctx context.Context, links []domain.CityTariff) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	if len(links) == 0 {
+		return domain.ErrBadParamInput
+	}
+	for _, l := range links {
+		if err := validation.Struct(l); err != nil {
+			return err
+		}
+	}
+	err := c.cityRepo.RemoveCityTariffs(ctx, links)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		c.audit.Record(ctx, domain.AuditDelete, domain.EntityCityTariff, l.Tariff, l, nil)
+	}
+	return nil
+}
+func (c CityUsecase) UpdateCity(ctx context.Context, cityID int, patch domain.CityPatch) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	if err := validation.Struct(patch); err != nil {
+		return err
+	}
+
+	before := c.citySnapshot(ctx, cityID)
+	err := c.cityRepo.UpdateCity(ctx, cityID, patch)
+	if err != nil {
+		return err
+	}
+	c.audit.Record(ctx, domain.AuditUpdate, domain.EntityCity, cityID, before, c.citySnapshot(ctx, cityID))
+	return nil
+}
+
+func (c CityUsecase) GetDeletedCities(ctx context.Context) ([]domain.City, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	cities, err := c.cityRepo.GetDeletedCities(ctx)
+	if err != nil {
+		return []domain.City{}, err
+	}
+	return cities, nil
+}
+
+func (c CityUsecase) RestoreCity(ctx context.Context, cityID int) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	err := c.cityRepo.RestoreCity(ctx, cityID)
+	if err != nil {
+		return err
+	}
+	c.audit.Record(ctx, domain.AuditRestore, domain.EntityCity, cityID, nil, c.citySnapshot(ctx, cityID))
 	return nil
 }
-func NewCityUsecase(repo domain.CityRepository, timeout time.Duration) domain.CityUsecase {
+
+func (c CityUsecase) LastModified(ctx context.Context) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	return c.cityRepo.LastModified(ctx)
+}
+
+// citySnapshot loads a city for the audit log; a failed lookup is logged and
+// returns nil, which Record does not write an entry for.
+func (c CityUsecase) citySnapshot(ctx context.Context, cityID int) interface{} {
+	city, err := c.cityRepo.GetCity(ctx, cityID)
+	if err != nil {
+		log.Printf("audit: loading city %d: %v", cityID, err)
+		return nil
+	}
+	return city
+}
+
+func NewCityUsecase(repo domain.CityRepository, audit domain.AuditUsecase, timeout time.Duration) domain.CityUsecase {
 	return &CityUsecase{
 		cityRepo:       repo,
+		audit:          audit,
 		contextTimeout: timeout,
 	}
 }
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+
+	EntityTariff     = "tariff"
+	EntityTariffType = "tariff_type"
+	EntityIcon       = "icon"
+	EntityCity       = "city"
+	EntityCityTariff = "city_tariff"
+
+	EntityTariffTypeTariff = "tariff_type_tariff"
+)
+
+type AuditEntry struct {
+	ID         int             `json:"id" db:"id"`
+	Actor      string          `json:"actor" db:"actor"`
+	Action     string          `json:"action" db:"action"`
+	EntityType string          `json:"entity_type" db:"entity_type"`
+	EntityID   int             `json:"entity_id" db:"entity_id"`
+	Before     json.RawMessage `json:"before" db:"before"`
+	After      json.RawMessage `json:"after" db:"after"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter narrows GetEntries; zero fields are not filtered on.
+type AuditFilter struct {
+	EntityType string
+	EntityID   int
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+type AuditUsecase interface {
+	// Record stores a change made by the caller found in ctx. before and
+	// after are snapshots of the entity and may be nil.
+	Record(ctx context.Context, action string, entityType string, entityID int, before interface{}, after interface{})
+	GetEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
+
+type AuditRepository interface {
+	AddEntry(ctx context.Context, entry AuditEntry) error
+	GetEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
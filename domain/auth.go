@@ -0,0 +1,50 @@
+package domain
+
+import "context"
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Allows reports whether r grants at least the permissions of required.
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[required]
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Principal is the authenticated caller of a request. For user logins
+// Subject is the user ID and SessionID the login session; for API keys it is
+// "api-key:" and the start of the key's SHA-256, which tells keys apart in
+// the audit log without revealing them.
+type Principal struct {
+	Subject   string `json:"subject"`
+	Role      Role   `json:"role"`
+	SessionID string `json:"-"`
+}
+
+type principalCtxKey struct{}
+
+// ContextWithPrincipal attaches the caller to ctx so usecases can see it.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalCtxKey{}).(Principal)
+	return principal, ok
+}
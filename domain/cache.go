@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores serialized read results. It is best effort: a backend error
+// behaves like a miss and never fails the request.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	// DeletePrefix drops every key starting with prefix.
+	DeletePrefix(ctx context.Context, prefix string)
+}
@@ -1,26 +1,70 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type City struct {
-	Id   int    `json:"id,omitempty"`
-	Name string `json:"name,omitempty" validate:"required"`
+	Id        int        `json:"id,omitempty"`
+	Name      string     `json:"name,omitempty" validate:"required,max=255"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// CityPatch holds a partial city update; nil fields are left unchanged.
+type CityPatch struct {
+	Name *string `json:"name" validate:"omitempty,max=255"`
 }
 type CityTariff struct {
-	City   int `json:"city_id"`
-	Tariff int `json:"tariff_id"`
+	City   int `json:"city_id" db:"city_id" validate:"gt=0"`
+	Tariff int `json:"tariff_id" db:"tariff_id" validate:"gt=0"`
+}
+
+// CityTariffLinks is the body of the bulk link endpoints. The side given in
+// the URL is left out: POST /cities/:id/tariffs takes TariffIDs and
+// POST /tariffs/:id/cities takes CityIDs.
+type CityTariffLinks struct {
+	CityIDs   []int `json:"city_ids"`
+	TariffIDs []int `json:"tariff_ids"`
 }
 
 type CityUsecase interface {
-	GetCities(ctx context.Context) ([]City, error)
-	RemoveCity(ctx context.Context, Id int) error
+	GetCities(ctx context.Context, page PageRequest) (Page[City], error)
+	GetCity(ctx context.Context, Id int) (City, error)
+	// RemoveCity fails with DependantsError while tariffs are offered in
+	// the city unless cascade is set, which unlinks them.
+	RemoveCity(ctx context.Context, Id int, cascade bool) error
 	AddCity(ctx context.Context, city City) error
 	RemoveCityTariff(ctx context.Context, tariff CityTariff) error
+	UpdateCity(ctx context.Context, Id int, patch CityPatch) error
+	GetDeletedCities(ctx context.Context) ([]City, error)
+	RestoreCity(ctx context.Context, Id int) error
+
+	AddCityTariff(ctx context.Context, tariff CityTariff) error
+	// AddCityTariffs and RemoveCityTariffs change many links at once;
+	// existing links are skipped when adding.
+	AddCityTariffs(ctx context.Context, links []CityTariff) error
+	RemoveCityTariffs(ctx context.Context, links []CityTariff) error
+	// LastModified is the time of the last write to the cities table.
+	LastModified(ctx context.Context) (time.Time, error)
 }
 
 type CityRepository interface {
-	GetCities(ctx context.Context) ([]City, error)
-	RemoveCity(ctx context.Context, Id int) error
-	AddCity(ctx context.Context, city City) error
+	GetCities(ctx context.Context, page PageRequest) (Page[City], error)
+	GetCity(ctx context.Context, Id int) (City, error)
+	// RemoveCity returns the tariff links cascade detached.
+	RemoveCity(ctx context.Context, Id int, cascade bool) (Cascade, error)
+	AddCity(ctx context.Context, city City) (int, error)
 	RemoveCityTariff(ctx context.Context, tariff CityTariff) error
+	UpdateCity(ctx context.Context, Id int, patch CityPatch) error
+	GetDeletedCities(ctx context.Context) ([]City, error)
+	RestoreCity(ctx context.Context, Id int) error
+
+	AddCityTariff(ctx context.Context, tariff CityTariff) error
+	// AddCityTariffs and RemoveCityTariffs change many links at once;
+	// existing links are skipped when adding.
+	AddCityTariffs(ctx context.Context, links []CityTariff) error
+	RemoveCityTariffs(ctx context.Context, links []CityTariff) error
+	// LastModified is the time of the last write to the cities table.
+	LastModified(ctx context.Context) (time.Time, error)
 }
@@ -0,0 +1,23 @@
+package domain
+
+// Dependant is a row that references another one and keeps it from being
+// deleted.
+type Dependant struct {
+	Entity string `json:"entity"`
+	ID     int    `json:"id" db:"id"`
+	Name   string `json:"name" db:"name"`
+}
+
+// DependantsError is the ErrConflict returned when a delete without cascade
+// finds dependants. They are listed in the error details.
+func DependantsError(dependants []Dependant) error {
+	return NewError(ErrConflict, "item is still referenced").WithDetails(dependants)
+}
+
+// Cascade holds the rows a delete with cascade removed along with the
+// entity, so each gets an audit entry of its own.
+type Cascade struct {
+	TariffTypes     []TariffType
+	TariffTypeLinks []TariffTypeLink
+	CityTariffs     []CityTariff
+}
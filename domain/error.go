@@ -1,11 +1,84 @@
 package domain
 
-import "errors"
+// ErrorCode is the machine-readable kind of an error, sent to clients in
+// the "code" field of every error response.
+type ErrorCode string
+
+const (
+	CodeInternal     ErrorCode = "internal"
+	CodeNotFound     ErrorCode = "not_found"
+	CodeConflict     ErrorCode = "conflict"
+	CodeBadParam     ErrorCode = "bad_param"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	CodeForbidden    ErrorCode = "forbidden"
+	CodeValidation   ErrorCode = "validation_failed"
+	CodeTooLarge     ErrorCode = "too_large"
+	CodeUnsupported  ErrorCode = "unsupported_media_type"
+)
 
 var (
-	ErrInternalServerError = errors.New("internal Server Error")
-	ErrNotFound            = errors.New("your requested Item is not found")
-	ErrConflict            = errors.New("your Item already exist")
-	ErrBadParamInput       = errors.New("given Param is not valid")
-	UserAlreadyExist       = errors.New("user already exist")
+	ErrInternalServerError = &Error{Code: CodeInternal, Message: "internal Server Error"}
+	ErrNotFound            = &Error{Code: CodeNotFound, Message: "your requested Item is not found"}
+	ErrConflict            = &Error{Code: CodeConflict, Message: "your Item already exist"}
+	ErrBadParamInput       = &Error{Code: CodeBadParam, Message: "given Param is not valid"}
+	UserAlreadyExist       = &Error{Code: CodeConflict, Message: "user already exist", kind: ErrConflict}
+	ErrUnauthorized        = &Error{Code: CodeUnauthorized, Message: "authentication required"}
+	ErrForbidden           = &Error{Code: CodeForbidden, Message: "not enough permissions"}
+	ErrTooLarge            = &Error{Code: CodeTooLarge, Message: "the upload is too large"}
+	ErrUnsupportedMedia    = &Error{Code: CodeUnsupported, Message: "the upload type is not supported"}
 )
+
+// Error is an error that can be shown to clients. The variables above are
+// its kinds; NewError and WrapError derive errors that still match their
+// kind with errors.Is. Message and Details are sent to clients, Err is the
+// underlying cause and is only logged.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Details interface{}
+	Err     error
+
+	kind *Error
+}
+
+// NewError returns an error of kind with a message specific to this failure.
+func NewError(kind *Error, message string) *Error {
+	return &Error{Code: kind.Code, Message: message, kind: kind}
+}
+
+// WrapError returns an error of kind caused by cause.
+func WrapError(kind *Error, cause error) *Error {
+	return &Error{Code: kind.Code, Message: kind.Message, Err: cause, kind: kind}
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e *Error) WithDetails(details interface{}) *Error {
+	cp := *e
+	cp.Details = details
+	if cp.kind == nil {
+		cp.kind = e
+	}
+	return &cp
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether e was derived from target, so a wrapped not-found
+// error still matches ErrNotFound.
+func (e *Error) Is(target error) bool {
+	for k := e.kind; k != nil; k = k.kind {
+		if target == error(k) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,25 +3,28 @@ package domain
 import "context"
 
 type New struct {
-	Id int `json:"id,omitempty"`
+	Id int `json:"id,omitempty" form:"id"`
 
-	Title string `json:"title,omitempty" validate:"required"`
-	Body  string `json:"body,omitempty" validate:"required"`
+	Title string `json:"title,omitempty" form:"title" validate:"required"`
+	Body  string `json:"body,omitempty" form:"body" validate:"required"`
 
-	Date string `json:"date,omitempty" validate:"required,date=2006-01-02"`
-	//files
-	Image    string `json:"image,omitempty" validate:"required"`
-	Document string `json:"document,omitempty" validate:"required"`
+	Date string `json:"date,omitempty" form:"date" validate:"required,date=2006-01-02"`
+	// Storage keys of the files, set by NewUsecase.AddNew from the uploads;
+	// responses carry their URLs.
+	Image    string `json:"image,omitempty"`
+	Document string `json:"document,omitempty"`
 }
 
 type NewUsecase interface {
 	GetNews(ctx context.Context) ([]New, error)
 	RemoveNew(ctx context.Context, Id int) error
-	AddNew(ctx context.Context, new New) error
+	// AddNew stores the image (PNG, JPEG or WebP) and document (PDF) of the
+	// news, judged by their content.
+	AddNew(ctx context.Context, new New, image Upload, document Upload) error
 }
 
 type NewRepository interface {
 	GetNews(ctx context.Context) ([]New, error)
-	RemoveNew(ctx context.Context, Id int) error
+	RemoveNew(ctx context.Context, Id int) (New, error)
 	AddNew(ctx context.Context, new New) error
 }
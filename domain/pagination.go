@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+	// NoPageLimit returns every row. Only internal callers such as the
+	// legacy routes use it; clients are held to MaxPageLimit.
+	NoPageLimit = -1
+)
+
+// PageRequest selects one page of a list. Offset and Cursor are
+// alternatives: Cursor is the NextCursor of the previous page and wins when
+// both are set. Sort names a field of the listed entity; empty means the
+// list's default order.
+type PageRequest struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   string
+	Desc   bool
+}
+
+// Page is the envelope every paginated list is returned in.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Normalize clamps Limit and checks Sort against sorts, whose first entry
+// is the default.
+func (r *PageRequest) Normalize(sorts ...string) error {
+	switch {
+	case r.Limit == 0:
+		r.Limit = DefaultPageLimit
+	case r.Limit == NoPageLimit:
+	case r.Limit < 0:
+		return ErrBadParamInput
+	case r.Limit > MaxPageLimit:
+		r.Limit = MaxPageLimit
+	}
+	if r.Offset < 0 {
+		return ErrBadParamInput
+	}
+	if r.Sort == "" && len(sorts) > 0 {
+		r.Sort = sorts[0]
+		return nil
+	}
+	for _, s := range sorts {
+		if r.Sort == s {
+			return nil
+		}
+	}
+	return ErrBadParamInput
+}
+
+// Start returns the offset of the first row, taken from Cursor when set.
+func (r PageRequest) Start() (int, error) {
+	if r.Cursor != "" {
+		return ParseOffsetCursor(r.Cursor)
+	}
+	return r.Offset, nil
+}
+
+// OffsetCursor and ParseOffsetCursor encode the position of the next page
+// for lists that page by offset.
+func OffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func ParseOffsetCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrBadParamInput
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, ErrBadParamInput
+	}
+	return offset, nil
+}
+
+// NewPage builds the envelope for a list paged by offset, setting
+// NextCursor when rows remain after this page.
+func NewPage[T any](items []T, total int, r PageRequest, offset int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	page := Page[T]{Items: items, Total: total}
+	if r.Limit != NoPageLimit && offset+len(items) < total {
+		page.NextCursor = OffsetCursor(offset + len(items))
+	}
+	return page
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"io"
+)
+
+// FileStorage keeps uploaded files under slash separated keys such as
+// "icons/<hash>.svg" and knows the public URL each key is served from.
+// Only keys are stored in the database; URLs are built when responding, so
+// they follow the configured public URL. They never depend on the request,
+// since responses are shared through caches.
+type FileStorage interface {
+	Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// Upload is a file as received, before it is checked and stored. Size is the
+// size the client announced; reading Content stops past the configured limit
+// whatever it says.
+type Upload struct {
+	Size    int64
+	Content io.Reader
+}
+
+// AssetURL returns the URL the file stored under key is served from. Empty
+// keys, such as news without an image, stay empty.
+func AssetURL(fs FileStorage, key string) string {
+	if key == "" {
+		return ""
+	}
+	return fs.URL(key)
+}
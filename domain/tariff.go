@@ -2,67 +2,270 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
+// Tariff is offered in every city in Cities. City holds the first of them
+// for clients that predate multi-city tariffs.
 type Tariff struct {
 	Id               int          `json:"ID" db:"id"`
-	Price            float64      `json:"price" db:"price"`
-	PeriodPerPay     string       `json:"period_per_pay" db:"period_per_pay"`
-	Title            string       `json:"title" db:"title"`
-	Subtitle         string       `json:"subtitle" db:"subtitle"`
+	Price            float64      `json:"price" db:"price" validate:"gte=0"`
+	PeriodPerPay     string       `json:"period_per_pay" db:"period_per_pay" validate:"required,max=50"`
+	Title            string       `json:"title" db:"title" validate:"required,max=255"`
+	Subtitle         string       `json:"subtitle" db:"subtitle" validate:"max=255"`
 	ShortDescription string       `json:"short_description" db:"short_description"`
 	Types            []TariffType `json:"tariff_type"`
-	City             int          `json:"city_id"`
+	City             int          `json:"city_id" validate:"gte=0"`
+	Cities           []int        `json:"cities" validate:"dive,gt=0"`
+	Status           TariffStatus `json:"status" db:"status" validate:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt        *time.Time   `json:"publish_at" db:"publish_at" validate:"required_if=Status scheduled"`
+	UnpublishAt      *time.Time   `json:"unpublish_at" db:"unpublish_at"`
+	DeletedAt        *time.Time   `json:"deleted_at,omitempty" db:"deleted_at"`
 }
+
+// TariffStatus is the publishing state of a tariff. Only published tariffs
+// are shown to anonymous clients; the scheduler moves scheduled tariffs to
+// published at PublishAt and published ones to archived at UnpublishAt.
+type TariffStatus string
+
+const (
+	TariffDraft     TariffStatus = "draft"
+	TariffScheduled TariffStatus = "scheduled"
+	TariffPublished TariffStatus = "published"
+	TariffArchived  TariffStatus = "archived"
+)
+
+func (s TariffStatus) Valid() bool {
+	switch s {
+	case TariffDraft, TariffScheduled, TariffPublished, TariffArchived:
+		return true
+	}
+	return false
+}
+
+// Live reports whether the tariff is publicly visible at now. It also checks
+// the publishing window so a tariff never shows early or late while waiting
+// for the next scheduler run.
+func (t Tariff) Live(now time.Time) bool {
+	if t.Status != TariffPublished {
+		return false
+	}
+	if t.PublishAt != nil && t.PublishAt.After(now) {
+		return false
+	}
+	return t.UnpublishAt == nil || t.UnpublishAt.After(now)
+}
+
+// CheckSchedule returns a *ValidationError unless the publishing window is
+// consistent: a scheduled tariff needs PublishAt, and UnpublishAt must come
+// after PublishAt.
+func (t Tariff) CheckSchedule() error {
+	var fields []FieldError
+	if t.Status == TariffScheduled && t.PublishAt == nil {
+		fields = append(fields, FieldError{Field: "publish_at", Rule: "required_if", Message: "is required when status is scheduled"})
+	}
+	if t.PublishAt != nil && t.UnpublishAt != nil && !t.UnpublishAt.After(*t.PublishAt) {
+		fields = append(fields, FieldError{Field: "unpublish_at", Rule: "gtfield", Message: "must be after publish_at"})
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// CheckCities returns a *ValidationError unless the tariff is offered in at
+// least one city, given in City or Cities.
+func (t Tariff) CheckCities() error {
+	if t.City == 0 && len(t.Cities) == 0 {
+		return noCityError()
+	}
+	return nil
+}
+
+func noCityError() error {
+	return &ValidationError{Fields: []FieldError{{Field: "cities", Rule: "required", Message: "must list at least one city"}}}
+}
+
 type TariffType struct {
 	ID          int           `json:"ID" db:"ID"`
-	Type        int           `json:"type" db:"Type"`
+	Type        int           `json:"type" db:"Type" validate:"gt=0"`
 	TypeName    string        `json:"type_name" db:"typeName"`
-	Name        string        `json:"name" db:"name"`
-	Description []Description `json:"description" db:"description"`
-	Title       string        `json:"title" db:"Title"`
-	Subtitle    string        `json:"subtitle" db:"Subtitle"`
-	Icon        int           `json:"icon" db:"icon"`
+	Name        string        `json:"name" db:"name" validate:"required,max=255"`
+	Description []Description `json:"description" db:"description" validate:"dive"`
+	Title       string        `json:"title" db:"Title" validate:"max=255"`
+	Subtitle    string        `json:"subtitle" db:"Subtitle" validate:"max=255"`
+	Icon        int           `json:"icon" db:"icon" validate:"gt=0"`
 	IconPath    string        `json:"icon_path" db:"icon_path"`
 }
 type Description struct {
-	Title string `json:"title"`
+	Title string `json:"title" validate:"required"`
 	Body  string `json:"body"`
 }
 
+// Icon is an uploaded icon file. Path is its storage key, which handlers
+// replace with the public URL. Raster icons get PNG thumbnails scaled down
+// to each configured size they exceed. Hash is the SHA-256 of the uploaded
+// content; icons stored before hashing was introduced have none.
 type Icon struct {
-	ID   int    `json:"ID" db:"id"`
+	ID          int         `json:"ID" db:"id"`
+	Path        string      `json:"path" db:"path" validate:"required"`
+	ContentType string      `json:"content_type" db:"content_type"`
+	Hash        string      `json:"-" db:"hash"`
+	Thumbnails  []Thumbnail `json:"thumbnails" db:"-"`
+}
+
+type Thumbnail struct {
+	Size int    `json:"size" db:"size"`
 	Path string `json:"path" db:"path"`
 }
+
+// TariffTypeLink links a tariff type to a tariff.
+type TariffTypeLink struct {
+	TariffType int `json:"tariff_type_id" db:"tariff_type_id"`
+	Tariff     int `json:"tariff_id" db:"tariff_id"`
+}
+
 type Type struct {
 	ID   int    `json:"ID" db:"id"`
 	Name string `json:"name" db:"name"`
 }
 
+// TariffPatch holds a partial tariff update; nil fields are left unchanged.
+// When Types or Cities is set it replaces the tariff's tariff-type or city
+// links. ClearPublishAt and ClearUnpublishAt remove a date, which a nil
+// field cannot express.
+type TariffPatch struct {
+	Price            *float64      `json:"price" validate:"omitempty,gte=0"`
+	PeriodPerPay     *string       `json:"period_per_pay" validate:"omitempty,max=50"`
+	Title            *string       `json:"title" validate:"omitempty,max=255"`
+	Subtitle         *string       `json:"subtitle" validate:"omitempty,max=255"`
+	ShortDescription *string       `json:"short_description"`
+	Types            *[]TariffType `json:"tariff_type"`
+	Cities           *[]int        `json:"cities" validate:"omitempty,dive,gt=0"`
+	Status           *TariffStatus `json:"status" validate:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt        *time.Time    `json:"publish_at"`
+	UnpublishAt      *time.Time    `json:"unpublish_at"`
+	ClearPublishAt   bool          `json:"clear_publish_at" validate:"excluded_with=PublishAt"`
+	ClearUnpublishAt bool          `json:"clear_unpublish_at" validate:"excluded_with=UnpublishAt"`
+}
+
+// CheckCities returns a *ValidationError when the patch would leave the
+// tariff without cities.
+func (p TariffPatch) CheckCities() error {
+	if p.Cities != nil && len(*p.Cities) == 0 {
+		return noCityError()
+	}
+	return nil
+}
+
+// TariffQuery filters, sorts and pages a tariff search. Zero values leave
+// a filter off. Unlike the other lists the search pages by keyset, so its
+// cursors are not offsets.
+type TariffQuery struct {
+	PageRequest
+	CityID        int
+	MinPrice      *float64
+	MaxPrice      *float64
+	PeriodPerPay  string
+	TariffTypeIDs []int
+	TypeIDs       []int
+	Text          string
+	// LiveOnly is set by the usecase for callers that may not see drafts.
+	LiveOnly bool
+}
+
+const (
+	TariffSortID    = "id"
+	TariffSortPrice = "price"
+	TariffSortTitle = "title"
+)
+
+// TariffTypePatch holds a partial tariff type update; nil fields are left unchanged.
+type TariffTypePatch struct {
+	Type        *int           `json:"type" validate:"omitempty,gt=0"`
+	Name        *string        `json:"name" validate:"omitempty,max=255"`
+	Description *[]Description `json:"description" validate:"omitempty,dive"`
+	Title       *string        `json:"title" validate:"omitempty,max=255"`
+	Subtitle    *string        `json:"subtitle" validate:"omitempty,max=255"`
+	Icon        *int           `json:"icon" validate:"omitempty,gt=0"`
+}
+
 type TariffUsecase interface {
-	GetTypes(ctx context.Context) ([]Type, error)
-	GetTariffTypes(ctx context.Context) ([]TariffType, error)
+	GetTypes(ctx context.Context, page PageRequest) (Page[Type], error)
+	GetTariffTypes(ctx context.Context, page PageRequest) (Page[TariffType], error)
 	GetTariffs(ctx context.Context, id int) ([]Tariff, error)
-	GetIcons(ctx context.Context) ([]Icon, error)
+	GetIcons(ctx context.Context, page PageRequest) (Page[Icon], error)
+	GetTariff(ctx context.Context, id int) (Tariff, error)
+	GetTariffType(ctx context.Context, id int) (TariffType, error)
+	GetIcon(ctx context.Context, id int) (Icon, error)
 
 	AddTariffType(ctx context.Context, tType TariffType) error
-	RemoveTariffType(ctx context.Context, id int) error
+	// RemoveTariffType and RemoveIcon fail with DependantsError while other
+	// rows reference them unless cascade is set. Cascade unlinks the tariffs
+	// of a tariff type and deletes the tariff types using an icon.
+	RemoveTariffType(ctx context.Context, id int, cascade bool) error
 	RemoveTariff(ctx context.Context, Id int) error
 	AddTariff(ctx context.Context, tariff Tariff) error
-	AddIcon(ctx context.Context, icon Icon) error
-	RemoveIcon(ctx context.Context, id int) error
+	// AddIcon stores an uploaded icon. Uploading content that is already
+	// stored returns the existing icon instead of adding another.
+	AddIcon(ctx context.Context, upload Upload) (Icon, error)
+	RemoveIcon(ctx context.Context, id int, cascade bool) error
+
+	UpdateTariff(ctx context.Context, id int, patch TariffPatch) error
+	UpdateTariffType(ctx context.Context, id int, patch TariffTypePatch) error
+	// ReplaceIcon swaps the file of icon id for upload. It is ErrConflict
+	// when another icon already has the same content.
+	ReplaceIcon(ctx context.Context, id int, upload Upload) (Icon, error)
+
+	GetDeletedTariffs(ctx context.Context) ([]Tariff, error)
+	RestoreTariff(ctx context.Context, id int) error
+
+	SearchTariffs(ctx context.Context, query TariffQuery) (Page[Tariff], error)
+
+	// PublishScheduled applies due status transitions and returns the IDs
+	// of the tariffs it changed.
+	PublishScheduled(ctx context.Context) ([]int, error)
+	// LastModified is the time of the last write to any catalog table.
+	LastModified(ctx context.Context) (time.Time, error)
 }
 
 type TariffRepository interface {
-	GetTypes(ctx context.Context) ([]Type, error)
-	GetTariffTypes(ctx context.Context) ([]TariffType, error)
-	GetTariffs(ctx context.Context, id int) ([]Tariff, error)
-	GetIcons(ctx context.Context) ([]Icon, error)
+	GetTypes(ctx context.Context, page PageRequest) (Page[Type], error)
+	GetTariffTypes(ctx context.Context, page PageRequest) (Page[TariffType], error)
+	// GetTariffs returns the tariffs of a city; liveOnly limits them to
+	// the ones currently published.
+	GetTariffs(ctx context.Context, id int, liveOnly bool) ([]Tariff, error)
+	GetIcons(ctx context.Context, page PageRequest) (Page[Icon], error)
+	GetTariff(ctx context.Context, id int) (Tariff, error)
+	GetTariffType(ctx context.Context, id int) (TariffType, error)
+	GetIcon(ctx context.Context, id int) (Icon, error)
+	GetIconByHash(ctx context.Context, hash string) (Icon, error)
+	// SearchTariffs returns a page of the tariffs matching query. The
+	// cursor is opaque to callers; a malformed one is ErrBadParamInput.
+	SearchTariffs(ctx context.Context, query TariffQuery) (Page[Tariff], error)
 
-	AddTariffType(ctx context.Context, tType TariffType) error
-	RemoveTariffType(ctx context.Context, id int) error
+	AddTariffType(ctx context.Context, tType TariffType) (int, error)
+	// RemoveTariffType returns the tariff links cascade dropped.
+	RemoveTariffType(ctx context.Context, id int, cascade bool) (Cascade, error)
 	RemoveTariff(ctx context.Context, Id int) error
-	AddTariff(ctx context.Context, tariff Tariff) error
-	AddIcon(ctx context.Context, icon Icon) error
-	RemoveIcon(ctx context.Context, id int) (string, error)
+	AddTariff(ctx context.Context, tariff Tariff) (int, error)
+	AddIcon(ctx context.Context, icon Icon) (int, error)
+	// RemoveIcon returns the removed icon so its files can be deleted, and
+	// the tariff types and links cascade deleted with it.
+	RemoveIcon(ctx context.Context, id int, cascade bool) (Icon, Cascade, error)
+
+	UpdateTariff(ctx context.Context, id int, patch TariffPatch) error
+	UpdateTariffType(ctx context.Context, id int, patch TariffTypePatch) error
+	// ReplaceIcon points the icon at new files and returns the previous
+	// state of the icon.
+	ReplaceIcon(ctx context.Context, icon Icon) (Icon, error)
+
+	GetDeletedTariffs(ctx context.Context) ([]Tariff, error)
+	RestoreTariff(ctx context.Context, id int) error
+
+	// PublishDue publishes scheduled tariffs whose publish_at has passed and
+	// archives published ones whose unpublish_at has passed. It returns the
+	// tariffs it changed with only Id and the Status they had before set.
+	PublishDue(ctx context.Context) ([]Tariff, error)
+	LastModified(ctx context.Context) (time.Time, error)
 }
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTariffCheckCities(t *testing.T) {
+	tests := []struct {
+		name   string
+		tariff Tariff
+		ok     bool
+	}{
+		{name: "legacy city", tariff: Tariff{City: 1}, ok: true},
+		{name: "cities", tariff: Tariff{Cities: []int{1, 2}}, ok: true},
+		{name: "none", tariff: Tariff{}},
+		{name: "empty list", tariff: Tariff{Cities: []int{}}},
+	}
+	for _, tt := range tests {
+		err := tt.tariff.CheckCities()
+		if tt.ok != (err == nil) {
+			t.Errorf("%s: CheckCities() = %v", tt.name, err)
+		}
+		var verr *ValidationError
+		if err != nil && (!errors.As(err, &verr) || verr.Fields[0].Field != "cities") {
+			t.Errorf("%s: CheckCities() = %v, want a validation error on cities", tt.name, err)
+		}
+	}
+
+	empty := []int{}
+	if (TariffPatch{Cities: &empty}).CheckCities() == nil {
+		t.Error("a patch clearing every city passed")
+	}
+	if err := (TariffPatch{}).CheckCities(); err != nil {
+		t.Errorf("a patch leaving the cities alone failed: %v", err)
+	}
+}
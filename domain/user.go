@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	Login        string    `json:"login" db:"login"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         Role      `json:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Session backs one issued login token; removing it logs the token out.
+type Session struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+type RegisterRequest struct {
+	Login    string `json:"login" validate:"required,max=100"`
+	Password string `json:"password" validate:"min=8,maxbytes=72"`
+	Role     Role   `json:"role" validate:"oneof=viewer editor admin"`
+}
+
+type LoginRequest struct {
+	Login    string `json:"login" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"min=8,maxbytes=72"`
+}
+
+// SessionValidator reports whether a login session is still active.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, id string) error
+}
+
+type UserUsecase interface {
+	SessionValidator
+	Register(ctx context.Context, req RegisterRequest) (User, error)
+	Login(ctx context.Context, req LoginRequest) (User, Session, error)
+	Logout(ctx context.Context, sessionID string) error
+	ChangePassword(ctx context.Context, userID int, req ChangePasswordRequest) error
+}
+
+type UserRepository interface {
+	AddUser(ctx context.Context, user User) (User, error)
+	GetUser(ctx context.Context, id int) (User, error)
+	GetUserByLogin(ctx context.Context, login string) (User, error)
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+
+	AddSession(ctx context.Context, session Session) error
+	GetSession(ctx context.Context, id string) (Session, error)
+	RemoveSession(ctx context.Context, id string) error
+	RemoveUserSessions(ctx context.Context, userID int) error
+}
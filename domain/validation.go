@@ -0,0 +1,25 @@
+package domain
+
+import "strings"
+
+// FieldError describes one field that failed validation. Field is the JSON
+// path of the field, such as "title" or "cities[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request. Handlers render
+// it as 422 Unprocessable Entity.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, f.Field+" "+f.Message)
+	}
+	return "validation failed: " + strings.Join(fields, ", ")
+}
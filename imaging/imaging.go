@@ -0,0 +1,251 @@
+// Package imaging checks and processes uploaded images and documents.
+package imaging
+
+import (
+	"bytes"
+	"encoding/xml"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"spektr-pages-api/domain"
+	"strings"
+)
+
+const (
+	SVG  = "image/svg+xml"
+	PNG  = "image/png"
+	JPEG = "image/jpeg"
+	WebP = "image/webp"
+	PDF  = "application/pdf"
+)
+
+// Ext is the file extension stored files of each accepted type get.
+var Ext = map[string]string{
+	SVG:  ".svg",
+	PNG:  ".png",
+	JPEG: ".jpg",
+	WebP: ".webp",
+	PDF:  ".pdf",
+}
+
+// Sniff returns the type of data judged by its content, not by the file
+// name or the type the client claimed, and fails unless it is one of
+// allowed.
+func Sniff(data []byte, allowed ...string) (string, error) {
+	ct := http.DetectContentType(data)
+	if isSVG(data) {
+		ct = SVG
+	}
+	for _, a := range allowed {
+		if ct == a {
+			return ct, nil
+		}
+	}
+	return "", domain.NewError(domain.ErrUnsupportedMedia, "the file type is not allowed").WithDetails(map[string][]string{
+		"allowed": allowed,
+	})
+}
+
+// isSVG reports whether the first element of data is <svg>.
+func isSVG(data []byte) bool {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return false
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "svg"
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+// CheckRaster makes sure data decodes as an image no larger than maxDimension
+// pixels on either side, which also keeps thumbnailing from being fed a
+// decompression bomb.
+func CheckRaster(data []byte, maxDimension int) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return domain.NewError(domain.ErrBadParamInput, "the image is damaged")
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return domain.NewError(domain.ErrTooLarge, "the image is too large").WithDetails(map[string]int{
+			"max_dimension": maxDimension,
+		})
+	}
+	return nil
+}
+
+// Thumb is a PNG encoded thumbnail.
+type Thumb struct {
+	Size int
+	Data []byte
+}
+
+// Thumbnails scales the raster image in data down to fit in a size×size
+// square for each of sizes. Sizes the image already fits in are skipped,
+// since thumbnails are never scaled up.
+func Thumbnails(data []byte, sizes []int) ([]Thumb, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, domain.NewError(domain.ErrBadParamInput, "the image is damaged")
+	}
+	b := src.Bounds()
+	var thumbs []Thumb
+	for _, size := range sizes {
+		w, h := b.Dx(), b.Dy()
+		if w <= size && h <= size {
+			continue
+		}
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, domain.WrapError(domain.ErrInternalServerError, err)
+		}
+		thumbs = append(thumbs, Thumb{Size: size, Data: buf.Bytes()})
+	}
+	return thumbs, nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Elements that run scripts or embed other documents, dropped with their
+// content.
+var forbiddenElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"audio":         true,
+	"video":         true,
+}
+
+// Animation elements, which are dropped when they animate a link.
+var animationElements = map[string]bool{
+	"animate":          true,
+	"animatemotion":    true,
+	"animatetransform": true,
+	"set":              true,
+}
+
+// SanitizeSVG returns data with scripts removed: forbidden elements, event
+// handler attributes, links other than to fragments of the document and any
+// attribute mentioning javascript:. Comments, processing instructions and
+// DTDs are dropped as well. The document is rewritten from its tokens, so
+// anything the XML parser does not accept is rejected.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+	var buf bytes.Buffer
+	var open []xml.Name
+	skip := 0 // depth inside a dropped element
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, domain.NewError(domain.ErrBadParamInput, "the SVG is not well-formed")
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || droppedElement(t) {
+				skip++
+				continue
+			}
+			if len(open) == 0 && (buf.Len() > 0 || t.Name.Local != "svg") {
+				return nil, domain.NewError(domain.ErrBadParamInput, "the SVG must have a single <svg> root")
+			}
+			open = append(open, t.Name)
+			buf.WriteByte('<')
+			writeName(&buf, t.Name)
+			for _, a := range t.Attr {
+				if !safeAttr(a) {
+					continue
+				}
+				buf.WriteByte(' ')
+				writeName(&buf, a.Name)
+				buf.WriteString(`="`)
+				xml.EscapeText(&buf, []byte(a.Value))
+				buf.WriteByte('"')
+			}
+			buf.WriteByte('>')
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, domain.NewError(domain.ErrBadParamInput, "the SVG is not well-formed")
+			}
+			open = open[:len(open)-1]
+			buf.WriteString("</")
+			writeName(&buf, t.Name)
+			buf.WriteByte('>')
+		case xml.CharData:
+			if skip == 0 && len(open) > 0 {
+				xml.EscapeText(&buf, t)
+			}
+		}
+	}
+	if buf.Len() == 0 || len(open) > 0 {
+		return nil, domain.NewError(domain.ErrBadParamInput, "the SVG is not well-formed")
+	}
+	return buf.Bytes(), nil
+}
+
+func droppedElement(t xml.StartElement) bool {
+	name := strings.ToLower(t.Name.Local)
+	if forbiddenElements[name] {
+		return true
+	}
+	if animationElements[name] {
+		for _, a := range t.Attr {
+			if a.Name.Local == "attributeName" && strings.HasSuffix(strings.ToLower(a.Value), "href") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func safeAttr(a xml.Attr) bool {
+	name := strings.ToLower(a.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return false
+	}
+	if name == "href" && !strings.HasPrefix(strings.TrimSpace(a.Value), "#") {
+		return false
+	}
+	value := strings.ToLower(strings.Join(strings.Fields(a.Value), ""))
+	return !strings.Contains(value, "javascript:")
+}
+
+func writeName(buf *bytes.Buffer, n xml.Name) {
+	if n.Space != "" {
+		buf.WriteString(n.Space)
+		buf.WriteByte(':')
+	}
+	buf.WriteString(n.Local)
+}
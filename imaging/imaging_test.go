@@ -0,0 +1,263 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"spektr-pages-api/domain"
+	"testing"
+)
+
+func TestSanitizeSVG(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "plain shapes are kept",
+			in:   `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><circle r="4"/></svg>`,
+			want: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><circle r="4"></circle></svg>`,
+		},
+		{
+			name: "script is dropped with its content",
+			in:   `<svg><script>alert(1)</script><g><script type="text/javascript"><![CDATA[alert(2)]]></script></g></svg>`,
+			want: `<svg><g></g></svg>`,
+		},
+		{
+			name: "foreignObject is dropped whatever its case",
+			in:   `<svg><foreignObject><div xmlns="http://www.w3.org/1999/xhtml">x</div></foreignObject><FOREIGNOBJECT/></svg>`,
+			want: `<svg></svg>`,
+		},
+		{
+			name: "event handlers are dropped",
+			in:   `<svg onload="alert(1)"><rect onClick="alert(2)" ONMOUSEOVER="alert(3)" width="1"/></svg>`,
+			want: `<svg><rect width="1"></rect></svg>`,
+		},
+		{
+			name: "javascript href is dropped",
+			in:   `<svg><a href="javascript:alert(1)"><rect/></a></svg>`,
+			want: `<svg><a><rect></rect></a></svg>`,
+		},
+		{
+			name: "javascript xlink:href in mixed case and whitespace is dropped",
+			in:   `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><a xlink:href=" JaVa&#x09;Script :alert(1)"><rect/></a></svg>`,
+			want: `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><a><rect></rect></a></svg>`,
+		},
+		{
+			name: "javascript in any other attribute is dropped",
+			in:   `<svg><rect style="background: url('java script:alert(1)')" width="1"/></svg>`,
+			want: `<svg><rect width="1"></rect></svg>`,
+		},
+		{
+			name: "fragment href on use is kept",
+			in:   `<svg><use href=" #dot"/></svg>`,
+			want: `<svg><use href=" #dot"></use></svg>`,
+		},
+		{
+			name: "external href on use is dropped",
+			in:   `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use href="https://evil.example/x.svg#a"/><use xlink:href="data:image/svg+xml,&lt;svg/&gt;"/></svg>`,
+			want: `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use></use><use></use></svg>`,
+		},
+		{
+			name: "animate of href is dropped",
+			in:   `<svg><a><animate attributeName="href" to="javascript:alert(1)"/><set attributeName="xlink:HREF" to="https://evil.example"/><rect/></a></svg>`,
+			want: `<svg><a><rect></rect></a></svg>`,
+		},
+		{
+			name: "animate of other attributes is kept",
+			in:   `<svg><rect><animate attributeName="width" from="1" to="2"/></rect></svg>`,
+			want: `<svg><rect><animate attributeName="width" from="1" to="2"></animate></rect></svg>`,
+		},
+		{
+			name: "comments and processing instructions are dropped",
+			in:   `<?xml version="1.0"?><!-- hi --><svg><!-- <script>alert(1)</script> --></svg>`,
+			want: `<svg></svg>`,
+		},
+		{
+			name:    "two roots",
+			in:      `<svg></svg><svg></svg>`,
+			wantErr: domain.ErrBadParamInput,
+		},
+		{
+			name:    "root other than svg",
+			in:      `<html><svg></svg></html>`,
+			wantErr: domain.ErrBadParamInput,
+		},
+		{
+			name:    "unclosed element",
+			in:      `<svg><g></svg>`,
+			wantErr: domain.ErrBadParamInput,
+		},
+		{
+			name:    "unclosed root",
+			in:      `<svg>`,
+			wantErr: domain.ErrBadParamInput,
+		},
+		{
+			name:    "not XML",
+			in:      `svg`,
+			wantErr: domain.ErrBadParamInput,
+		},
+		{
+			name:    "empty",
+			in:      ``,
+			wantErr: domain.ErrBadParamInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeSVG([]byte(tt.in))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SanitizeSVG() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SanitizeSVG() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("SanitizeSVG() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	raster := []string{PNG, JPEG, WebP}
+	tests := []struct {
+		name    string
+		data    []byte
+		allowed []string
+		want    string
+		wantErr error
+	}{
+		{name: "png", data: encodePNG(t, 1, 1), allowed: raster, want: PNG},
+		{name: "jpeg", data: encodeJPEG(t, 1, 1), allowed: raster, want: JPEG},
+		{name: "webp", data: []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x18\x00\x00\x00"), allowed: raster, want: WebP},
+		{name: "svg", data: []byte(`<?xml version="1.0"?>` + "\n" + `<svg></svg>`), allowed: []string{SVG}, want: SVG},
+		{name: "pdf", data: []byte("%PDF-1.7\n"), allowed: []string{PDF}, want: PDF},
+		{name: "jpeg not allowed", data: encodeJPEG(t, 1, 1), allowed: []string{SVG, PNG, WebP}, wantErr: domain.ErrUnsupportedMedia},
+		{name: "svg not allowed", data: []byte(`<svg></svg>`), allowed: raster, wantErr: domain.ErrUnsupportedMedia},
+		{name: "html", data: []byte(`<html><script>alert(1)</script></html>`), allowed: append(raster, SVG), wantErr: domain.ErrUnsupportedMedia},
+		{name: "text", data: []byte("hello"), allowed: raster, wantErr: domain.ErrUnsupportedMedia},
+		{name: "empty", data: nil, allowed: raster, wantErr: domain.ErrUnsupportedMedia},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff(tt.data, tt.allowed...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Sniff() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Sniff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Sniff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRaster(t *testing.T) {
+	if err := CheckRaster(encodePNG(t, 64, 32), 64); err != nil {
+		t.Errorf("CheckRaster(64x32, 64) error = %v", err)
+	}
+	if err := CheckRaster(encodePNG(t, 32, 65), 64); !errors.Is(err, domain.ErrTooLarge) {
+		t.Errorf("CheckRaster(32x65, 64) error = %v, want %v", err, domain.ErrTooLarge)
+	}
+	if err := CheckRaster([]byte("\x89PNG\r\n\x1a\nbroken"), 64); !errors.Is(err, domain.ErrBadParamInput) {
+		t.Errorf("CheckRaster(broken) error = %v, want %v", err, domain.ErrBadParamInput)
+	}
+}
+
+func TestThumbnails(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		sizes  []int
+		want   []image.Point // one per thumbnail, in order
+		ofSize []int
+	}{
+		{
+			name:   "landscape",
+			data:   encodePNG(t, 200, 100),
+			sizes:  []int{32, 64, 128},
+			want:   []image.Point{{32, 16}, {64, 32}, {128, 64}},
+			ofSize: []int{32, 64, 128},
+		},
+		{
+			name:   "portrait jpeg",
+			data:   encodeJPEG(t, 50, 100),
+			sizes:  []int{32, 64},
+			want:   []image.Point{{16, 32}, {32, 64}},
+			ofSize: []int{32, 64},
+		},
+		{
+			name:   "sizes the image fits in are skipped",
+			data:   encodePNG(t, 64, 64),
+			sizes:  []int{32, 64, 128},
+			want:   []image.Point{{32, 32}},
+			ofSize: []int{32},
+		},
+		{
+			name:   "a side never goes below one pixel",
+			data:   encodePNG(t, 300, 1),
+			sizes:  []int{32},
+			want:   []image.Point{{32, 1}},
+			ofSize: []int{32},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbs, err := Thumbnails(tt.data, tt.sizes)
+			if err != nil {
+				t.Fatalf("Thumbnails() error = %v", err)
+			}
+			if len(thumbs) != len(tt.want) {
+				t.Fatalf("Thumbnails() made %d thumbnails, want %d", len(thumbs), len(tt.want))
+			}
+			for i, thumb := range thumbs {
+				if thumb.Size != tt.ofSize[i] {
+					t.Errorf("thumbnail %d has size %d, want %d", i, thumb.Size, tt.ofSize[i])
+				}
+				cfg, err := png.DecodeConfig(bytes.NewReader(thumb.Data))
+				if err != nil {
+					t.Fatalf("thumbnail %d is not a PNG: %v", i, err)
+				}
+				if got := (image.Point{cfg.Width, cfg.Height}); got != tt.want[i] {
+					t.Errorf("thumbnail %d is %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := Thumbnails([]byte("not an image"), []int{32}); !errors.Is(err, domain.ErrBadParamInput) {
+		t.Errorf("Thumbnails(not an image) error = %v, want %v", err, domain.ErrBadParamInput)
+	}
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
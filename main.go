@@ -10,14 +10,32 @@ import (
 	"github.com/spf13/viper"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	_auditHttp "spektr-pages-api/audit/delivery/http"
+	_auditRepo "spektr-pages-api/audit/repository/postgres"
+	_auditUsecase "spektr-pages-api/audit/usecase"
+	_memoryCache "spektr-pages-api/cache/memory"
+	_redisCache "spektr-pages-api/cache/redis"
 	_cityHttp "spektr-pages-api/city/delivery/http"
 	_cityRepo "spektr-pages-api/city/repository/postgres"
 	_cityUsecase "spektr-pages-api/city/usecase"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"spektr-pages-api/migrations"
+	_newHttp "spektr-pages-api/news/delivery/http"
+	_newRepo "spektr-pages-api/news/repository/postgres"
+	_newUsecase "spektr-pages-api/news/usecase"
+	_localStorage "spektr-pages-api/storage/local"
+	_s3Storage "spektr-pages-api/storage/s3"
 	_tariffHttp "spektr-pages-api/tariff/delivery/http"
 	_tariffRepo "spektr-pages-api/tariff/repository/postgres"
 	_tariffUsecase "spektr-pages-api/tariff/usecase"
+	_userHttp "spektr-pages-api/users/delivery/http"
+	_userRepo "spektr-pages-api/users/repository/postgres"
+	_userUsecase "spektr-pages-api/users/usecase"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -29,6 +47,19 @@ func init() {
 		panic(err)
 	}
 
+	viper.SetDefault("server.legacy_routes", true)
+	viper.SetDefault("database.schema", "spektr")
+	viper.SetDefault("auth.session_ttl", "24h")
+	viper.SetDefault("scheduler.interval", "1m")
+	viper.SetDefault("cache.size", 1024)
+	viper.SetDefault("cache.ttl", "1m")
+	viper.SetDefault("icons.max_size", 1<<20)
+	viper.SetDefault("icons.max_dimension", 2048)
+	viper.SetDefault("icons.thumbnail_sizes", []int{32, 64, 128})
+	viper.SetDefault("news.max_image_size", 5<<20)
+	viper.SetDefault("news.max_image_dimension", 4096)
+	viper.SetDefault("news.max_document_size", 20<<20)
+
 	if viper.GetBool(`debug`) {
 		log.Println("Service RUN on DEBUG mode")
 	}
@@ -42,13 +73,14 @@ func main() {
 	dbUser := os.Getenv("DB_USER")
 	dbPass := os.Getenv("DB_PASSWORD")
 	dbName := os.Getenv("DB_NAME")
+	dbSchema := viper.GetString("database.schema")
 	// Construct the connection string
-	connection := fmt.Sprintf("postgres://%v:%v@db:%v/%v?sslmode=disable",
+	connection := fmt.Sprintf("postgres://%v:%v@db:%v/%v?sslmode=disable&search_path=%v",
 		dbUser,
 		dbPass,
 		dbPort,
-		dbName)
-	fmt.Println(connection)
+		dbName,
+		url.QueryEscape(dbSchema))
 	// Open a connection to the database
 	dbConn, err := sqlx.Open("postgres", connection)
 	if err != nil {
@@ -60,7 +92,6 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println(err)
 	defer func() {
 		// Close the database connection
 		err := dbConn.Close()
@@ -69,21 +100,96 @@ func main() {
 		}
 	}()
 
+	migrator, err := migrations.NewMigrator(dbConn, dbSchema)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// `main migrate <command>` runs migrations and exits without serving.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = runMigrate(migrator, os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if viper.GetBool("database.auto_migrate") {
+		err = migrator.Up(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	g := gin.Default()
-	g.Static("/assets", "./static")
 	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
 
-	tariffRepo := _tariffRepo.NewTariffRepository(dbConn)
-	tariffUcase := _tariffUsecase.NewTariffUsecase(tariffRepo, timeoutContext)
-	_tariffHttp.NewTariffHandler(g, tariffUcase)
-	cityRepo := _cityRepo.NewCityRepository(dbConn)
-	cityUcase := _cityUsecase.NewCityUsecase(cityRepo, timeoutContext)
-	_cityHttp.NewCityHandler(g, cityUcase)
+	// Users are created by admins only. The first admin is registered with
+	// POST /api/v1/users using an API key with the admin role, e.g.
+	// API_KEYS=<random key>:admin, which should be removed afterwards.
+	apiKeys, err := middleware.ParseAPIKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		log.Fatal("Invalid API_KEYS, expected key:role pairs")
+	}
+	jwtSecret := os.Getenv("JWT_SECRET")
+	switch {
+	case jwtSecret == "":
+		log.Println("JWT_SECRET is not set, bearer tokens are disabled")
+	case len(jwtSecret) < middleware.MinJWTKeySize:
+		log.Fatalf("JWT_SECRET must be at least %d bytes, e.g. the output of `openssl rand -hex 32`", middleware.MinJWTKeySize)
+	}
+	userRepo := _userRepo.NewUserRepository(dbConn, dbSchema)
+	userUcase := _userUsecase.NewUserUsecase(userRepo, viper.GetDuration("auth.session_ttl"), timeoutContext)
+	authMiddleware := middleware.NewAuthMiddleware([]byte(jwtSecret), apiKeys, userUcase)
+	g.Use(authMiddleware.Authenticate())
+	editor := authMiddleware.RequireRole(domain.RoleEditor)
+	_userHttp.NewUserHandler(g, userUcase, authMiddleware)
+
+	fileStorage, err := newFileStorage(g)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	auditRepo := _auditRepo.NewAuditRepository(dbConn, dbSchema)
+	auditUcase := _auditUsecase.NewAuditUsecase(auditRepo, timeoutContext)
+	_auditHttp.NewAuditHandler(g, auditUcase, authMiddleware.RequireRole(domain.RoleAdmin))
+
+	catalogCache, err := newCache()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cacheTTL := viper.GetDuration("cache.ttl")
+
+	tariffRepo := _tariffRepo.NewTariffRepository(dbConn, dbSchema)
+	tariffUcase := _tariffUsecase.NewTariffUsecase(tariffRepo, fileStorage, auditUcase, _tariffUsecase.IconOptions{
+		MaxSize:        viper.GetInt64("icons.max_size"),
+		MaxDimension:   viper.GetInt("icons.max_dimension"),
+		ThumbnailSizes: viper.GetIntSlice("icons.thumbnail_sizes"),
+	}, timeoutContext)
+	if catalogCache != nil {
+		tariffUcase = _tariffUsecase.NewCachedTariffUsecase(tariffUcase, catalogCache, cacheTTL)
+	}
+	_tariffHttp.NewTariffHandler(g, tariffUcase, fileStorage, editor)
+	cityRepo := _cityRepo.NewCityRepository(dbConn, dbSchema)
+	cityUcase := _cityUsecase.NewCityUsecase(cityRepo, auditUcase, timeoutContext)
+	if catalogCache != nil {
+		cityUcase = _cityUsecase.NewCachedCityUsecase(cityUcase, catalogCache, cacheTTL)
+	}
+	_cityHttp.NewCityHandler(g, cityUcase, editor)
+	newRepo := _newRepo.NewNewRepository(dbConn, dbSchema)
+	newUcase := _newUsecase.NewNewUsecase(newRepo, fileStorage, _newUsecase.UploadOptions{
+		MaxImageSize:      viper.GetInt64("news.max_image_size"),
+		MaxImageDimension: viper.GetInt("news.max_image_dimension"),
+		MaxDocumentSize:   viper.GetInt64("news.max_document_size"),
+	}, timeoutContext)
+	_newHttp.NewNewHandler(g, newUcase, fileStorage, editor)
 	server := &http.Server{
 		Addr:    viper.GetString("server.address"),
 		Handler: g,
 	}
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go runScheduler(schedulerCtx, tariffUcase, viper.GetDuration("scheduler.interval"))
+
 	// Start the server in a goroutine
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -97,6 +203,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopScheduler()
 
 	// Create a deadline for server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -109,3 +216,121 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+// newFileStorage builds the storage backend selected by storage.driver. The
+// local driver also serves its root directory under /assets. Responses link
+// to files below storage.public_url, which may point at a CDN; only the keys
+// are stored, so it can change at any time.
+func newFileStorage(g *gin.Engine) (domain.FileStorage, error) {
+	switch viper.GetString("storage.driver") {
+	case "s3":
+		return _s3Storage.NewS3FileStorage(context.Background(), _s3Storage.Config{
+			Endpoint:  viper.GetString("storage.s3.endpoint"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    viper.GetString("storage.s3.bucket"),
+			UseSSL:    viper.GetBool("storage.s3.use_ssl"),
+			PublicURL: viper.GetString("storage.public_url"),
+		})
+	default:
+		root := viper.GetString("storage.local.root")
+		if root == "" {
+			root = "./static"
+		}
+		// Without a public URL, links are relative to the API's own host.
+		publicURL := viper.GetString("storage.public_url")
+		if publicURL == "" {
+			publicURL = "/assets"
+		}
+		g.Static("/assets", root)
+		return _localStorage.NewLocalFileStorage(root, publicURL), nil
+	}
+}
+
+// newCache builds the catalog cache selected by cache.driver: "memory"
+// (the default), "redis" or "none", which returns nil. Entries also expire
+// after cache.ttl, which bounds how long a tariff stays listed past its
+// unpublish_at before the scheduler archives it.
+func newCache() (domain.Cache, error) {
+	switch viper.GetString("cache.driver") {
+	case "none":
+		return nil, nil
+	case "redis":
+		return _redisCache.NewRedisCache(context.Background(), _redisCache.Config{
+			Addr:     viper.GetString("cache.redis.addr"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       viper.GetInt("cache.redis.db"),
+		})
+	default:
+		return _memoryCache.NewMemoryCache(viper.GetInt("cache.size")), nil
+	}
+}
+
+// runScheduler publishes and archives tariffs as their publish_at and
+// unpublish_at pass, checking every interval until ctx is cancelled.
+func runScheduler(ctx context.Context, tariffs domain.TariffUsecase, interval time.Duration) {
+	// Status changes are audited as made by the scheduler, not anonymously.
+	ctx = domain.ContextWithPrincipal(ctx, domain.Principal{Subject: "scheduler", Role: domain.RoleAdmin})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ids, err := tariffs.PublishScheduled(ctx)
+		if err != nil {
+			log.Println("scheduler:", err)
+		} else if len(ids) > 0 {
+			log.Printf("scheduler: updated status of tariffs %v", ids)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runMigrate handles `migrate up`, `migrate down [n]`, `migrate status` and
+// `migrate to <version>`.
+func runMigrate(m *migrations.Migrator, args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|status|to <version>")
+	}
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			n, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("migrate down: invalid step count %q", args[1])
+			}
+		}
+		return m.Down(ctx, n)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate to <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("migrate to: invalid version %q", args[1])
+		}
+		return m.To(ctx, version)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("migrate: unknown command %q", args[0])
+	}
+}
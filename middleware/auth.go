@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"spektr-pages-api/domain"
+	"strings"
+	"time"
+)
+
+const principalKey = "principal"
+
+type Claims struct {
+	Role domain.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type AuthMiddleware struct {
+	jwtKey   []byte
+	apiKeys  map[string]domain.Role
+	sessions domain.SessionValidator
+}
+
+// MinJWTKeySize is the shortest HS256 key accepted, so the key cannot be
+// guessed to forge tokens.
+const MinJWTKeySize = 32
+
+// NewAuthMiddleware accepts HS256 bearer tokens signed with jwtKey and static
+// API keys sent in the X-API-Key header. An empty jwtKey disables tokens.
+// Tokens carrying a session ID (jti) are also checked against sessions.
+func NewAuthMiddleware(jwtKey []byte, apiKeys map[string]domain.Role, sessions domain.SessionValidator) *AuthMiddleware {
+	return &AuthMiddleware{
+		jwtKey:   jwtKey,
+		apiKeys:  apiKeys,
+		sessions: sessions,
+	}
+}
+
+// IssueToken signs a bearer token for principal that expires at expiresAt.
+func (m *AuthMiddleware) IssueToken(principal domain.Principal, expiresAt time.Time) (string, error) {
+	if len(m.jwtKey) == 0 {
+		return "", domain.ErrInternalServerError
+	}
+	claims := Claims{
+		Role: principal.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        principal.SessionID,
+			Subject:   principal.Subject,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.jwtKey)
+	if err != nil {
+		return "", domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return token, nil
+}
+
+// ParseAPIKeys reads "key:role" pairs separated by commas.
+func ParseAPIKeys(s string) (map[string]domain.Role, error) {
+	keys := map[string]domain.Role{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, role, ok := strings.Cut(pair, ":")
+		if !ok || key == "" || !domain.Role(role).Valid() {
+			return nil, domain.ErrBadParamInput
+		}
+		keys[key] = domain.Role(role)
+	}
+	return keys, nil
+}
+
+// Authenticate resolves the caller from the request credentials. Requests
+// without credentials pass through anonymously; invalid ones are rejected.
+func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			role, ok := m.apiKey(key)
+			if !ok {
+				AbortWithError(c, domain.ErrUnauthorized)
+				return
+			}
+			setPrincipal(c, domain.Principal{Subject: apiKeySubject(key), Role: role})
+			c.Next()
+			return
+		}
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+		if !strings.HasPrefix(header, "Bearer ") {
+			AbortWithError(c, domain.ErrUnauthorized)
+			return
+		}
+		principal, err := m.parseToken(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			AbortWithError(c, domain.ErrUnauthorized)
+			return
+		}
+		if principal.SessionID != "" && m.sessions != nil {
+			err = m.sessions.ValidateSession(c.Request.Context(), principal.SessionID)
+			if err != nil {
+				AbortWithError(c, err)
+				return
+			}
+		}
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// RequireRole rejects callers that are anonymous or lack role.
+func (m *AuthMiddleware) RequireRole(role domain.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			AbortWithError(c, domain.ErrUnauthorized)
+			return
+		}
+		if !principal.Role.Allows(role) {
+			AbortWithError(c, domain.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// setPrincipal stores the caller on the gin context and on the request
+// context, where usecases look it up.
+func setPrincipal(c *gin.Context, principal domain.Principal) {
+	c.Set(principalKey, principal)
+	c.Request = c.Request.WithContext(domain.ContextWithPrincipal(c.Request.Context(), principal))
+}
+
+// GetPrincipal returns the caller resolved by Authenticate.
+func GetPrincipal(c *gin.Context) (domain.Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return domain.Principal{}, false
+	}
+	principal, ok := v.(domain.Principal)
+	return principal, ok
+}
+
+func (m *AuthMiddleware) apiKey(key string) (domain.Role, bool) {
+	for k, role := range m.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// apiKeySubject identifies an API key by a prefix of its hash.
+func apiKeySubject(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api-key:" + hex.EncodeToString(sum[:6])
+}
+
+func (m *AuthMiddleware) parseToken(token string) (domain.Principal, error) {
+	if len(m.jwtKey) == 0 {
+		return domain.Principal{}, domain.ErrUnauthorized
+	}
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return m.jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !claims.Role.Valid() {
+		return domain.Principal{}, domain.ErrUnauthorized
+	}
+	return domain.Principal{Subject: claims.Subject, Role: claims.Role, SessionID: claims.ID}, nil
+}
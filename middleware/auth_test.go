@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"spektr-pages-api/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testJWTKey = []byte("0123456789abcdef0123456789abcdef")
+
+type fakeSessions map[string]bool
+
+func (s fakeSessions) ValidateSession(ctx context.Context, id string) error {
+	if !s[id] {
+		return domain.ErrUnauthorized
+	}
+	return nil
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func claimsFor(role domain.Role, sessionID string, expiresAt time.Time) Claims {
+	return Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewAuthMiddleware(testJWTKey, map[string]domain.Role{
+		"viewer-key": domain.RoleViewer,
+		"admin-key":  domain.RoleAdmin,
+	}, fakeSessions{"live": true})
+
+	hour := time.Now().Add(time.Hour)
+	valid, err := m.IssueToken(domain.Principal{Subject: "1", Role: domain.RoleEditor, SessionID: "live"}, hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		required   domain.Role
+		wantStatus int
+	}{
+		{name: "no credentials", required: domain.RoleViewer, wantStatus: http.StatusUnauthorized},
+		{name: "valid token", header: "Authorization", value: "Bearer " + valid, required: domain.RoleEditor, wantStatus: http.StatusOK},
+		{name: "valid token, role too low", header: "Authorization", value: "Bearer " + valid, required: domain.RoleAdmin, wantStatus: http.StatusForbidden},
+		{
+			name:   "expired token",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodHS256, testJWTKey,
+				claimsFor(domain.RoleAdmin, "", time.Now().Add(-time.Minute))),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "token without expiry",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodHS256, testJWTKey,
+				Claims{Role: domain.RoleAdmin}),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong algorithm",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodHS512, testJWTKey,
+				claimsFor(domain.RoleAdmin, "", hour)),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "unsigned token",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType,
+				claimsFor(domain.RoleAdmin, "", hour)),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong key",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte("fedcba9876543210fedcba9876543210"),
+				claimsFor(domain.RoleAdmin, "", hour)),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown role",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodHS256, testJWTKey,
+				claimsFor("root", "", hour)),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "ended session",
+			header: "Authorization",
+			value: "Bearer " + signToken(t, jwt.SigningMethodHS256, testJWTKey,
+				claimsFor(domain.RoleAdmin, "ended", hour)),
+			required:   domain.RoleViewer,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{name: "not a bearer token", header: "Authorization", value: "Basic dXNlcjpwYXNz", required: domain.RoleViewer, wantStatus: http.StatusUnauthorized},
+		{name: "valid API key", header: "X-API-Key", value: "admin-key", required: domain.RoleAdmin, wantStatus: http.StatusOK},
+		{name: "API key, role too low", header: "X-API-Key", value: "viewer-key", required: domain.RoleEditor, wantStatus: http.StatusForbidden},
+		{name: "bad API key", header: "X-API-Key", value: "admin-kex", required: domain.RoleViewer, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", m.Authenticate(), m.RequireRole(tt.required), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAnonymous(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewAuthMiddleware(testJWTKey, nil, nil)
+	r := gin.New()
+	r.GET("/", m.Authenticate(), func(c *gin.Context) {
+		if _, ok := GetPrincipal(c); ok {
+			t.Error("a request without credentials got a principal")
+		}
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAPIKeySubject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewAuthMiddleware(nil, map[string]domain.Role{"first": domain.RoleAdmin, "second": domain.RoleAdmin}, nil)
+	subjects := map[string]string{}
+	for _, key := range []string{"first", "second"} {
+		r := gin.New()
+		r.GET("/", m.Authenticate(), func(c *gin.Context) {
+			principal, _ := GetPrincipal(c)
+			subjects[key] = principal.Subject
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", key)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if subjects["first"] != apiKeySubject("first") || subjects["first"] == subjects["second"] {
+		t.Errorf("API key subjects = %v, want one per key", subjects)
+	}
+	for key, subject := range subjects {
+		if !strings.HasPrefix(subject, "api-key:") || strings.Contains(subject, key) {
+			t.Errorf("subject of %q = %q", key, subject)
+		}
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys(" a:admin , b:viewer,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys["a"] != domain.RoleAdmin || keys["b"] != domain.RoleViewer || len(keys) != 2 {
+		t.Errorf("ParseAPIKeys() = %v", keys)
+	}
+	for _, s := range []string{"a", ":admin", "a:root"} {
+		if _, err := ParseAPIKeys(s); err == nil {
+			t.Errorf("ParseAPIKeys(%q) accepted a bad pair", s)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ConditionalJSON writes obj with an ETag hashed from the body and, when
+// lastModified is set, a Last-Modified header. It answers 304 instead when
+// the request's If-None-Match or If-Modified-Since shows the client already
+// has it. lastModified must be read before obj so it never claims a newer
+// state than the body holds.
+func ConditionalJSON(c *gin.Context, lastModified time.Time, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		RenderError(c, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := c.Writer.Header()
+	header.Set("ETag", etag)
+	// Editors see unpublished tariffs, so the body depends on credentials.
+	header.Add("Vary", "Authorization, X-API-Key")
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// notModified evaluates the validators as RFC 9110 orders them: when
+// If-None-Match is present If-Modified-Since is ignored.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
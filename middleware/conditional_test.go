@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		ConditionalJSON(c, modified, gin.H{"result": "ok"})
+	})
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := serve(nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.String() != `{"result":"ok"}` {
+		t.Fatalf("first response = %d %q, ETag %q", first.Code, first.Body, etag)
+	}
+	if got := first.Header().Get("Last-Modified"); got != "Fri, 01 Mar 2024 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "matching etag", headers: map[string]string{"If-None-Match": etag}, want: http.StatusNotModified},
+		{name: "weak etag in a list", headers: map[string]string{"If-None-Match": `"other", W/` + etag}, want: http.StatusNotModified},
+		{name: "any etag", headers: map[string]string{"If-None-Match": "*"}, want: http.StatusNotModified},
+		{name: "other etag", headers: map[string]string{"If-None-Match": `"other"`}, want: http.StatusOK},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, want: http.StatusNotModified},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": before}, want: http.StatusOK},
+		{name: "bad date", headers: map[string]string{"If-Modified-Since": "yesterday"}, want: http.StatusOK},
+		{
+			name:    "If-None-Match wins over a matching If-Modified-Since",
+			headers: map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": after},
+			want:    http.StatusOK,
+		},
+		{
+			name:    "If-None-Match wins over a stale If-Modified-Since",
+			headers: map[string]string{"If-None-Match": etag, "If-Modified-Since": before},
+			want:    http.StatusNotModified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.headers)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", w.Header().Get("ETag"), etag)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 has a body: %q", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"spektr-pages-api/domain"
+)
+
+// ErrorResponse is the body of every error response. Error is the human
+// readable message, Code the stable machine-readable kind and Details any
+// extra data the kind defines, such as the invalid fields of a validation
+// error.
+type ErrorResponse struct {
+	Error   string           `json:"error"`
+	Code    domain.ErrorCode `json:"code"`
+	Details interface{}      `json:"details,omitempty"`
+}
+
+var statusByCode = map[domain.ErrorCode]int{
+	domain.CodeInternal:     http.StatusInternalServerError,
+	domain.CodeNotFound:     http.StatusNotFound,
+	domain.CodeConflict:     http.StatusConflict,
+	domain.CodeBadParam:     http.StatusBadRequest,
+	domain.CodeUnauthorized: http.StatusUnauthorized,
+	domain.CodeForbidden:    http.StatusForbidden,
+	domain.CodeValidation:   http.StatusUnprocessableEntity,
+	domain.CodeTooLarge:     http.StatusRequestEntityTooLarge,
+	domain.CodeUnsupported:  http.StatusUnsupportedMediaType,
+}
+
+// ErrorStatus is the HTTP status err is rendered with.
+func ErrorStatus(err error) int {
+	status, _ := errorResponse(err)
+	return status
+}
+
+// RenderError writes err as an ErrorResponse. Errors that are not domain
+// errors become 500 without exposing their text; the cause of every 5xx is
+// logged.
+func RenderError(c *gin.Context, err error) {
+	status, body := errorResponse(err)
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	c.JSON(status, body)
+}
+
+// AbortWithError is RenderError for middleware that stops the chain.
+func AbortWithError(c *gin.Context, err error) {
+	RenderError(c, err)
+	c.Abort()
+}
+
+func errorResponse(err error) (int, ErrorResponse) {
+	var verr *domain.ValidationError
+	if errors.As(err, &verr) {
+		return http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   verr.Error(),
+			Code:    domain.CodeValidation,
+			Details: verr.Fields,
+		}
+	}
+	var derr *domain.Error
+	if !errors.As(err, &derr) {
+		derr = domain.ErrInternalServerError
+	}
+	status, ok := statusByCode[derr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return status, ErrorResponse{
+		Error:   derr.Message,
+		Code:    derr.Code,
+		Details: derr.Details,
+	}
+}
@@ -0,0 +1,287 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the embedded migrations to one schema. Every migration
+// runs in its own transaction with search_path set to that schema, so the
+// SQL files use unqualified table names.
+type Migrator struct {
+	db         *sqlx.DB
+	schema     string
+	migrations []Migration
+}
+
+func NewMigrator(db *sqlx.DB, schema string) (*Migrator, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		schema:     schema,
+		migrations: migrations,
+	}, nil
+}
+
+func load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migrations: unexpected file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := files.ReadFile("sql/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) table() string {
+	return pq.QuoteIdentifier(m.schema) + ".schema_migrations"
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+pq.QuoteIdentifier(m.schema))
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table()+` (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := m.db.QueryxContext(ctx, "SELECT version, applied_at FROM "+m.table())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// Version returns the highest applied migration, or 0 on an empty database.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if err := m.init(ctx); err != nil {
+		return 0, err
+	}
+	var version int
+	err := m.db.GetContext(ctx, &version, "SELECT COALESCE(MAX(version), 0) FROM "+m.table())
+	return version, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// lock takes a session-level advisory lock for the schema on a connection of
+// its own and returns the function releasing it, so service replicas that
+// migrate at startup wait for each other instead of applying the same
+// migration twice.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := "schema_migrations:" + m.schema
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", key)
+		if err != nil {
+			log.Printf("migrations: releasing the lock: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the last n applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0 && n > 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if err := m.run(ctx, mig, false); err != nil {
+			return err
+		}
+		n--
+	}
+	return nil
+}
+
+// To migrates up or down until version is the last applied migration.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && !m.exists(version) {
+		return fmt.Errorf("migrations: unknown version %d", version)
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; ok && mig.Version > version {
+			if err := m.run(ctx, mig, false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+			if err := m.run(ctx, mig, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) exists(version int) bool {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) run(ctx context.Context, mig Migration, up bool) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	_, err = tx.ExecContext(ctx, "SET LOCAL search_path TO "+pq.QuoteIdentifier(m.schema))
+	if err != nil {
+		return err
+	}
+	body := mig.Down
+	if up {
+		body = mig.Up
+	}
+	if _, err = tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migrations: %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+m.table()+" (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+m.table()+" WHERE version = $1", mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
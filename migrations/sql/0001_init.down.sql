@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS t_new;
+DROP TABLE IF EXISTS t_tariff_type_tariff;
+DROP TABLE IF EXISTS t_city_tariff;
+DROP TABLE IF EXISTS t_tariff;
+DROP TABLE IF EXISTS t_tariff_type;
+DROP TABLE IF EXISTS t_icon;
+DROP TABLE IF EXISTS t_type;
+DROP TABLE IF EXISTS t_city;
@@ -0,0 +1,57 @@
+-- The schema the service ran on before it had migrations. Databases from
+-- that time already have these tables, so they are only created when
+-- missing and such a database is adopted as being at version 1.
+CREATE TABLE IF NOT EXISTS t_city (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_type (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_icon (
+    id   SERIAL PRIMARY KEY,
+    path TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_tariff_type (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT    NOT NULL,
+    description JSONB   NOT NULL DEFAULT '[]',
+    title       TEXT    NOT NULL DEFAULT '',
+    subtitle    TEXT    NOT NULL DEFAULT '',
+    icon        INTEGER NOT NULL REFERENCES t_icon (id),
+    type        INTEGER NOT NULL REFERENCES t_type (id)
+);
+
+CREATE TABLE IF NOT EXISTS t_tariff (
+    id                SERIAL PRIMARY KEY,
+    price             NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    period_per_pay    TEXT           NOT NULL DEFAULT '',
+    title             TEXT           NOT NULL,
+    subtitle          TEXT           NOT NULL DEFAULT '',
+    short_description TEXT           NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS t_city_tariff (
+    city_id   INTEGER NOT NULL REFERENCES t_city (id) ON DELETE CASCADE,
+    tariff_id INTEGER NOT NULL REFERENCES t_tariff (id) ON DELETE CASCADE,
+    PRIMARY KEY (city_id, tariff_id)
+);
+
+CREATE TABLE IF NOT EXISTS t_tariff_type_tariff (
+    tariff_id      INTEGER NOT NULL REFERENCES t_tariff (id) ON DELETE CASCADE,
+    tariff_type_id INTEGER NOT NULL REFERENCES t_tariff_type (id) ON DELETE CASCADE,
+    PRIMARY KEY (tariff_id, tariff_type_id)
+);
+
+CREATE TABLE IF NOT EXISTS t_new (
+    id       SERIAL PRIMARY KEY,
+    title    TEXT NOT NULL,
+    body     TEXT NOT NULL,
+    date     DATE NOT NULL,
+    image    TEXT NOT NULL DEFAULT '',
+    document TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_session;
+DROP TABLE IF EXISTS t_user;
@@ -0,0 +1,16 @@
+CREATE TABLE t_user (
+    id            SERIAL PRIMARY KEY,
+    login         TEXT        NOT NULL UNIQUE,
+    password_hash TEXT        NOT NULL,
+    role          TEXT        NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE t_session (
+    id         UUID PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES t_user (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX t_session_user_id_idx ON t_session (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE t_audit (
+    id          BIGSERIAL PRIMARY KEY,
+    actor       TEXT        NOT NULL,
+    action      TEXT        NOT NULL,
+    entity_type TEXT        NOT NULL,
+    entity_id   INTEGER     NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX t_audit_entity_idx ON t_audit (entity_type, entity_id);
+CREATE INDEX t_audit_created_at_idx ON t_audit (created_at);
@@ -0,0 +1,4 @@
+DELETE FROM t_tariff WHERE deleted_at IS NOT NULL;
+DELETE FROM t_city WHERE deleted_at IS NOT NULL;
+ALTER TABLE t_tariff DROP COLUMN deleted_at;
+ALTER TABLE t_city DROP COLUMN deleted_at;
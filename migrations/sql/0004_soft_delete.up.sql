@@ -0,0 +1,2 @@
+ALTER TABLE t_tariff ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE t_city ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+DROP INDEX t_tariff_status_idx;
+ALTER TABLE t_tariff DROP COLUMN unpublish_at;
+ALTER TABLE t_tariff DROP COLUMN publish_at;
+ALTER TABLE t_tariff DROP COLUMN status;
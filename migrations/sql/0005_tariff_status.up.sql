@@ -0,0 +1,7 @@
+-- Existing tariffs are already public, so they start out published.
+ALTER TABLE t_tariff ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE t_tariff ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE t_tariff ADD COLUMN publish_at TIMESTAMPTZ;
+ALTER TABLE t_tariff ADD COLUMN unpublish_at TIMESTAMPTZ;
+CREATE INDEX t_tariff_status_idx ON t_tariff (status);
@@ -0,0 +1,9 @@
+DROP TRIGGER t_tariff_type_tariff_change ON t_tariff_type_tariff;
+DROP TRIGGER t_city_tariff_change ON t_city_tariff;
+DROP TRIGGER t_tariff_change ON t_tariff;
+DROP TRIGGER t_tariff_type_change ON t_tariff_type;
+DROP TRIGGER t_icon_change ON t_icon;
+DROP TRIGGER t_type_change ON t_type;
+DROP TRIGGER t_city_change ON t_city;
+DROP FUNCTION touch_table_change();
+DROP TABLE t_table_change;
@@ -0,0 +1,35 @@
+-- Last write time per catalog table, kept by triggers so deletes count too.
+-- It backs the Last-Modified header of the catalog endpoints.
+CREATE TABLE t_table_change (
+    table_name TEXT PRIMARY KEY,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE FUNCTION touch_table_change() RETURNS trigger AS $$
+BEGIN
+    EXECUTE format(
+        'INSERT INTO %I.t_table_change (table_name, updated_at) VALUES ($1, clock_timestamp())
+         ON CONFLICT (table_name) DO UPDATE SET updated_at = excluded.updated_at',
+        TG_TABLE_SCHEMA)
+    USING TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER t_city_change AFTER INSERT OR UPDATE OR DELETE ON t_city
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+CREATE TRIGGER t_type_change AFTER INSERT OR UPDATE OR DELETE ON t_type
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+CREATE TRIGGER t_icon_change AFTER INSERT OR UPDATE OR DELETE ON t_icon
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+CREATE TRIGGER t_tariff_type_change AFTER INSERT OR UPDATE OR DELETE ON t_tariff_type
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+CREATE TRIGGER t_tariff_change AFTER INSERT OR UPDATE OR DELETE ON t_tariff
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+CREATE TRIGGER t_city_tariff_change AFTER INSERT OR UPDATE OR DELETE ON t_city_tariff
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+CREATE TRIGGER t_tariff_type_tariff_change AFTER INSERT OR UPDATE OR DELETE ON t_tariff_type_tariff
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+
+INSERT INTO t_table_change (table_name)
+VALUES ('t_city'), ('t_type'), ('t_icon'), ('t_tariff_type'), ('t_tariff'), ('t_city_tariff'), ('t_tariff_type_tariff');
@@ -0,0 +1,5 @@
+DELETE FROM t_table_change WHERE table_name = 't_icon_thumbnail';
+DROP TABLE t_icon_thumbnail;
+DROP INDEX t_icon_hash_key;
+ALTER TABLE t_icon DROP COLUMN content_type;
+ALTER TABLE t_icon DROP COLUMN hash;
@@ -0,0 +1,17 @@
+-- Icons are deduplicated by the SHA-256 of their content. Icons uploaded
+-- before this have no hash and are never matched.
+ALTER TABLE t_icon ADD COLUMN hash TEXT;
+ALTER TABLE t_icon ADD COLUMN content_type TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX t_icon_hash_key ON t_icon (hash);
+
+CREATE TABLE t_icon_thumbnail (
+    icon_id INTEGER NOT NULL REFERENCES t_icon (id) ON DELETE CASCADE,
+    size    INTEGER NOT NULL,
+    path    TEXT    NOT NULL,
+    PRIMARY KEY (icon_id, size)
+);
+
+CREATE TRIGGER t_icon_thumbnail_change AFTER INSERT OR UPDATE OR DELETE ON t_icon_thumbnail
+    FOR EACH STATEMENT EXECUTE FUNCTION touch_table_change();
+
+INSERT INTO t_table_change (table_name) VALUES ('t_icon_thumbnail');
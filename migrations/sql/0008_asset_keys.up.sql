@@ -0,0 +1,11 @@
+-- Files used to be stored as the URL they were served from. Only the storage
+-- key is kept now and the API builds the URL, so the base URL can change.
+-- The keys are the directory the files were uploaded to plus their name.
+UPDATE t_icon SET path = 'icons/' || regexp_replace(path, '^.*/', '')
+    WHERE path <> '' AND path <> 'icons/' || regexp_replace(path, '^.*/', '');
+UPDATE t_icon_thumbnail SET path = 'icons/' || regexp_replace(path, '^.*/', '')
+    WHERE path <> 'icons/' || regexp_replace(path, '^.*/', '');
+UPDATE t_new SET image = 'images/' || regexp_replace(image, '^.*/', '')
+    WHERE image <> '' AND image <> 'images/' || regexp_replace(image, '^.*/', '');
+UPDATE t_new SET document = 'documents/' || regexp_replace(document, '^.*/', '')
+    WHERE document <> '' AND document <> 'documents/' || regexp_replace(document, '^.*/', '');
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	domain "spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"strconv"
+)
+
+type NewHandler struct {
+	NUsecase domain.NewUsecase
+	Storage  domain.FileStorage
+}
+
+func NewNewHandler(g *gin.Engine, us domain.NewUsecase, fs domain.FileStorage, auth gin.HandlerFunc) {
+	handler := &NewHandler{
+		NUsecase: us,
+		Storage:  fs,
+	}
+
+	v1 := g.Group("/api/v1")
+	v1.GET("/news", handler.GetNews)
+	v1.POST("/news", auth, handler.AddNew)
+	v1.DELETE("/news/:id", auth, handler.DeleteNew)
+
+	// Routes from before /api/v1, reading ids from JSON bodies. Kept while
+	// clients migrate; disable with server.legacy_routes=false.
+	if !viper.GetBool("server.legacy_routes") {
+		return
+	}
+	g.GET("/news", handler.GetNews)
+	g.POST("/new", auth, handler.AddNew)
+	g.DELETE("/new", auth, handler.RemoveNew)
+}
+
+func (h *NewHandler) GetNews(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	news, err := h.NUsecase.GetNews(ctx)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	for i := range news {
+		news[i].Image = domain.AssetURL(h.Storage, news[i].Image)
+		news[i].Document = domain.AssetURL(h.Storage, news[i].Document)
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"result": news,
+	})
+}
+
+// multipartOverhead allows for the form fields and part headers around the
+// files when capping the request body.
+const multipartOverhead = 64 << 10
+
+func (h *NewHandler) AddNew(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	maxSize := viper.GetInt64("news.max_image_size") + viper.GetInt64("news.max_document_size")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	var news domain.New
+	if err := c.ShouldBind(&news); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			middleware.RenderError(c, domain.NewError(domain.ErrTooLarge, "the files are too large").WithDetails(map[string]int64{
+				"max_image_size":    viper.GetInt64("news.max_image_size"),
+				"max_document_size": viper.GetInt64("news.max_document_size"),
+			}))
+			return
+		}
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid news data"))
+		return
+	}
+	image, imageFile, err := formUpload(c, "image")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	defer imageFile.Close()
+	document, documentFile, err := formUpload(c, "document")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	defer documentFile.Close()
+
+	err = h.NUsecase.AddNew(ctx, news, image, document)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"result": "ok"})
+}
+
+func (h *NewHandler) RemoveNew(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var id domain.New
+	if err := c.ShouldBindJSON(&id); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid news data"))
+		return
+	}
+
+	err := h.NUsecase.RemoveNew(ctx, id.Id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+
+func (h *NewHandler) DeleteNew(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid news id"))
+		return
+	}
+
+	err = h.NUsecase.RemoveNew(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+
+// formUpload opens the file sent as field of the multipart form.
+func formUpload(c *gin.Context, field string) (domain.Upload, io.Closer, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		return domain.Upload{}, nil, domain.NewError(domain.ErrBadParamInput, "The "+field+" file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return domain.Upload{}, nil, domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return domain.Upload{Size: header.Size, Content: file}, file, nil
+}
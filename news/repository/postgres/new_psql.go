@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/pgerr"
+	"strings"
+)
+
+type psqlNewRepository struct {
+	db     *sqlx.DB
+	schema string
+}
+
+func NewNewRepository(conn *sqlx.DB, schema string) domain.NewRepository {
+	return &psqlNewRepository{conn, pq.QuoteIdentifier(schema)}
+}
+
+// q qualifies the {schema} placeholders in query with the configured schema.
+func (p *psqlNewRepository) q(query string) string {
+	return strings.ReplaceAll(query, "{schema}", p.schema)
+}
+
+func (p *psqlNewRepository) GetNews(ctx context.Context) ([]domain.New, error) {
+	var news []domain.New
+	query := p.q(`SELECT id, title, body, to_char(date, 'YYYY-MM-DD') AS date, image, document FROM {schema}.t_new ORDER BY date DESC, id DESC`)
+	err := p.db.SelectContext(ctx, &news, query)
+	if err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return news, nil
+}
+
+func (p *psqlNewRepository) AddNew(ctx context.Context, new domain.New) error {
+	query := p.q(`INSERT INTO {schema}.t_new (title, body, date, image, document) VALUES ($1, $2, $3, $4, $5)`)
+	stmt, err := p.db.PrepareContext(ctx, query)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	defer stmt.Close() // Close the statement after use
+	_, err = stmt.ExecContext(ctx, new.Title, new.Body, new.Date, new.Image, new.Document)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	return nil
+}
+
+func (p *psqlNewRepository) RemoveNew(ctx context.Context, id int) (domain.New, error) {
+	var new domain.New
+	err := p.db.GetContext(ctx, &new, p.q(`DELETE FROM {schema}.t_new WHERE id = $1 RETURNING id, image, document`), id)
+	if err != nil {
+		return domain.New{}, pgerr.Map(err)
+	}
+	return new, nil
+}
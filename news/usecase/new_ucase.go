@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/imaging"
+	"spektr-pages-api/validation"
+	"time"
+)
+
+type NewUsecase struct {
+	newRepo        domain.NewRepository
+	storage        domain.FileStorage
+	uploads        UploadOptions
+	contextTimeout time.Duration
+}
+
+func (n NewUsecase) GetNews(ctx context.Context) ([]domain.New, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.contextTimeout)
+	defer cancel()
+
+	news, err := n.newRepo.GetNews(ctx)
+	if err != nil {
+		return []domain.New{}, err
+	}
+	return news, nil
+}
+
+// AddNew checks and stores the image and document before the news itself,
+// and deletes them again when the news cannot be added.
+func (n NewUsecase) AddNew(ctx context.Context, new domain.New, image domain.Upload, document domain.Upload) error {
+	ctx, cancel := context.WithTimeout(ctx, n.contextTimeout)
+	defer cancel()
+
+	if err := validation.Struct(new); err != nil {
+		return err
+	}
+	imageData, imageType, err := readUpload(image, n.uploads.MaxImageSize, imageTypes)
+	if err != nil {
+		return err
+	}
+	if err := imaging.CheckRaster(imageData, n.uploads.MaxImageDimension); err != nil {
+		return err
+	}
+	documentData, documentType, err := readUpload(document, n.uploads.MaxDocumentSize, documentTypes)
+	if err != nil {
+		return err
+	}
+
+	new.Image, err = n.saveFile(ctx, "images", imageData, imageType)
+	if err != nil {
+		return err
+	}
+	new.Document, err = n.saveFile(ctx, "documents", documentData, documentType)
+	if err != nil {
+		n.removeFile(ctx, new.Image)
+		return err
+	}
+	err = n.newRepo.AddNew(ctx, new)
+	if err != nil {
+		n.removeFile(ctx, new.Image)
+		n.removeFile(ctx, new.Document)
+		return err
+	}
+	return nil
+}
+
+func (n NewUsecase) RemoveNew(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, n.contextTimeout)
+	defer cancel()
+
+	new, err := n.newRepo.RemoveNew(ctx, id)
+	if err != nil {
+		return err
+	}
+	n.removeFile(ctx, new.Image)
+	n.removeFile(ctx, new.Document)
+	return nil
+}
+
+// removeFile deletes the stored file with key. Empty keys are ignored so
+// news saved without an attachment can still be removed. The news is already
+// gone by then, so a failure only leaves an orphaned file and is logged.
+func (n NewUsecase) removeFile(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := n.storage.Delete(ctx, key); err != nil {
+		log.Printf("news: removing %s: %v", key, err)
+	}
+}
+
+func NewNewUsecase(repo domain.NewRepository, fs domain.FileStorage, uploads UploadOptions, timeout time.Duration) domain.NewUsecase {
+	return &NewUsecase{
+		newRepo:        repo,
+		storage:        fs,
+		uploads:        uploads,
+		contextTimeout: timeout,
+	}
+}
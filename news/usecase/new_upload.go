@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"io"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/imaging"
+)
+
+// UploadOptions limits news attachments. The sizes are in bytes,
+// MaxImageDimension in pixels per side.
+type UploadOptions struct {
+	MaxImageSize      int64
+	MaxImageDimension int
+	MaxDocumentSize   int64
+}
+
+// Only types that browsers will not run as a page are accepted, since the
+// files are served from the API's own origin; SVG and HTML are not.
+var (
+	imageTypes    = []string{imaging.PNG, imaging.JPEG, imaging.WebP}
+	documentTypes = []string{imaging.PDF}
+)
+
+// readUpload reads upload within maxSize and checks its content is one of
+// allowed. It returns the data and the sniffed type.
+func readUpload(upload domain.Upload, maxSize int64, allowed []string) ([]byte, string, error) {
+	tooLarge := domain.NewError(domain.ErrTooLarge, "the file is too large").WithDetails(map[string]int64{
+		"max_size": maxSize,
+	})
+	if upload.Size > maxSize {
+		return nil, "", tooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(upload.Content, maxSize+1))
+	if err != nil {
+		return nil, "", domain.WrapError(domain.ErrBadParamInput, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", tooLarge
+	}
+	contentType, err := imaging.Sniff(data, allowed...)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, contentType, nil
+}
+
+// saveFile stores data under dir with a generated name and the extension of
+// its sniffed type, and returns the key.
+func (n NewUsecase) saveFile(ctx context.Context, dir string, data []byte, contentType string) (string, error) {
+	key := dir + "/" + uuid.NewString() + imaging.Ext[contentType]
+	err := n.storage.Save(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
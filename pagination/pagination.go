@@ -0,0 +1,59 @@
+// Package pagination reads page requests from query parameters and turns
+// them into SQL for the offset-paged lists.
+package pagination
+
+import (
+	"github.com/gin-gonic/gin"
+	"spektr-pages-api/domain"
+	"strconv"
+)
+
+// FromQuery reads the limit, offset, cursor, sort and direction query
+// parameters.
+func FromQuery(c *gin.Context) (domain.PageRequest, error) {
+	var page domain.PageRequest
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil || page.Limit < 1 {
+			return page, domain.ErrBadParamInput
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if page.Offset, err = strconv.Atoi(v); err != nil {
+			return page, domain.ErrBadParamInput
+		}
+	}
+	page.Cursor = c.Query("cursor")
+	page.Sort = c.Query("sort")
+	switch c.Query("direction") {
+	case "", "asc":
+	case "desc":
+		page.Desc = true
+	default:
+		return page, domain.ErrBadParamInput
+	}
+	return page, nil
+}
+
+// OrderBy builds the ORDER BY list for page from the sort field to column
+// mapping. The "id" column breaks ties so pages never overlap.
+func OrderBy(sorts map[string]string, page domain.PageRequest) string {
+	direction := " ASC"
+	if page.Desc {
+		direction = " DESC"
+	}
+	id := sorts["id"]
+	column, ok := sorts[page.Sort]
+	if !ok || column == id {
+		return id + direction
+	}
+	return column + direction + ", " + id + direction
+}
+
+// LimitArg is the LIMIT argument for page; NULL means no limit.
+func LimitArg(page domain.PageRequest) interface{} {
+	if page.Limit == domain.NoPageLimit {
+		return nil
+	}
+	return page.Limit
+}
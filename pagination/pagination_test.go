@@ -0,0 +1,68 @@
+package pagination
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"spektr-pages-api/domain"
+	"testing"
+)
+
+func TestFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query   string
+		want    domain.PageRequest
+		wantErr bool
+	}{
+		{query: "", want: domain.PageRequest{}},
+		{query: "limit=10&offset=20&sort=name&direction=desc", want: domain.PageRequest{Limit: 10, Offset: 20, Sort: "name", Desc: true}},
+		{query: "cursor=MjA&direction=asc", want: domain.PageRequest{Cursor: "MjA"}},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+		{query: "offset=x", wantErr: true},
+		{query: "direction=up", wantErr: true},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+		got, err := FromQuery(c)
+		if tt.wantErr {
+			if !errors.Is(err, domain.ErrBadParamInput) {
+				t.Errorf("FromQuery(%q) error = %v, want %v", tt.query, err, domain.ErrBadParamInput)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("FromQuery(%q) = %+v, %v; want %+v", tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	sorts := map[string]string{"id": "tt.id", "name": "tt.name"}
+	tests := []struct {
+		page domain.PageRequest
+		want string
+	}{
+		{page: domain.PageRequest{}, want: "tt.id ASC"},
+		{page: domain.PageRequest{Sort: "id", Desc: true}, want: "tt.id DESC"},
+		{page: domain.PageRequest{Sort: "name"}, want: "tt.name ASC, tt.id ASC"},
+		{page: domain.PageRequest{Sort: "name; DROP TABLE t_city", Desc: true}, want: "tt.id DESC"},
+	}
+	for _, tt := range tests {
+		if got := OrderBy(sorts, tt.page); got != tt.want {
+			t.Errorf("OrderBy(%+v) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestLimitArg(t *testing.T) {
+	if got := LimitArg(domain.PageRequest{Limit: 20}); got != 20 {
+		t.Errorf("LimitArg(20) = %v", got)
+	}
+	if got := LimitArg(domain.PageRequest{Limit: domain.NoPageLimit}); got != nil {
+		t.Errorf("LimitArg(NoPageLimit) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,56 @@
+// Package pgerr turns database errors into domain errors.
+package pgerr
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"spektr-pages-api/domain"
+)
+
+// Postgres error codes, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	UniqueViolation     = "23505"
+	ForeignKeyViolation = "23503"
+	CheckViolation      = "23514"
+)
+
+// Map returns err as a domain error, keeping err as the cause. A unique
+// violation is ErrConflict, a foreign key violation ErrBadParamInput (a
+// reference to a row that does not exist), as is a check violation (a value
+// a CHECK constraint rejects), no rows ErrNotFound and anything else
+// ErrInternalServerError. Domain errors pass through unchanged.
+func Map(err error) error {
+	if err == nil {
+		return nil
+	}
+	var derr *domain.Error
+	var verr *domain.ValidationError
+	if errors.As(err, &derr) || errors.As(err, &verr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.WrapError(domain.ErrNotFound, err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case UniqueViolation:
+			return domain.WrapError(domain.ErrConflict, err).WithDetails(constraint(pqErr))
+		case ForeignKeyViolation, CheckViolation:
+			return domain.WrapError(domain.ErrBadParamInput, err).WithDetails(constraint(pqErr))
+		}
+	}
+	return domain.WrapError(domain.ErrInternalServerError, err)
+}
+
+// Is reports whether err is a Postgres error with code.
+func Is(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+func constraint(pqErr *pq.Error) map[string]string {
+	return map[string]string{"constraint": pqErr.Constraint}
+}
@@ -0,0 +1,86 @@
+package pgerr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"reflect"
+	"spektr-pages-api/domain"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	notFound := domain.NewError(domain.ErrNotFound, "no such city")
+	tests := []struct {
+		name        string
+		err         error
+		want        *domain.Error
+		wantDetails interface{}
+	}{
+		{
+			name:        "unique violation",
+			err:         &pq.Error{Code: UniqueViolation, Constraint: "t_city_name_key"},
+			want:        domain.ErrConflict,
+			wantDetails: map[string]string{"constraint": "t_city_name_key"},
+		},
+		{
+			name:        "foreign key violation",
+			err:         &pq.Error{Code: ForeignKeyViolation, Constraint: "t_tariff_type_icon_fkey"},
+			want:        domain.ErrBadParamInput,
+			wantDetails: map[string]string{"constraint": "t_tariff_type_icon_fkey"},
+		},
+		{
+			name:        "check violation",
+			err:         &pq.Error{Code: CheckViolation, Constraint: "t_tariff_price_check"},
+			want:        domain.ErrBadParamInput,
+			wantDetails: map[string]string{"constraint": "t_tariff_price_check"},
+		},
+		{
+			name:        "wrapped violation",
+			err:         fmt.Errorf("inserting: %w", &pq.Error{Code: UniqueViolation, Constraint: "t_icon_hash_key"}),
+			want:        domain.ErrConflict,
+			wantDetails: map[string]string{"constraint": "t_icon_hash_key"},
+		},
+		{name: "no rows", err: sql.ErrNoRows, want: domain.ErrNotFound},
+		{name: "other postgres error", err: &pq.Error{Code: "40001"}, want: domain.ErrInternalServerError},
+		{name: "other error", err: errors.New("connection reset"), want: domain.ErrInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Map(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Fatalf("Map() = %v, want %v", got, tt.want)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("Map() = %v does not keep the cause %v", got, tt.err)
+			}
+			var derr *domain.Error
+			errors.As(got, &derr)
+			if tt.wantDetails != nil && !reflect.DeepEqual(derr.Details, tt.wantDetails) {
+				t.Errorf("Map() details = %v, want %v", derr.Details, tt.wantDetails)
+			}
+		})
+	}
+
+	if got := Map(nil); got != nil {
+		t.Errorf("Map(nil) = %v", got)
+	}
+	if got := Map(notFound); got != notFound {
+		t.Errorf("Map() changed a domain error to %v", got)
+	}
+	verr := &domain.ValidationError{}
+	if got := Map(verr); got != verr {
+		t.Errorf("Map() changed a validation error to %v", got)
+	}
+}
+
+func TestIs(t *testing.T) {
+	err := fmt.Errorf("updating: %w", &pq.Error{Code: CheckViolation})
+	if !Is(err, CheckViolation) {
+		t.Error("Is() missed a wrapped check violation")
+	}
+	if Is(err, UniqueViolation) || Is(errors.New("other"), UniqueViolation) {
+		t.Error("Is() matched the wrong error")
+	}
+}
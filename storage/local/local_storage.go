@@ -0,0 +1,92 @@
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"spektr-pages-api/domain"
+	"strings"
+)
+
+type localFileStorage struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalFileStorage stores files below root; baseURL is the address root is
+// served from, e.g. "https://cdn.example.com/assets" or, relative to the API's
+// own host, "/assets".
+func NewLocalFileStorage(root string, baseURL string) domain.FileStorage {
+	return &localFileStorage{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (l *localFileStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", domain.ErrBadParamInput
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
+
+func (l *localFileStorage) Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	_, err = io.Copy(f, r)
+	// Close reports write errors the copy may not have seen, such as a
+	// full disk.
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Never leave a partial file to be served.
+		os.Remove(p)
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return nil
+}
+
+func (l *localFileStorage) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if err != nil && !os.IsNotExist(err) {
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return nil
+}
+
+func (l *localFileStorage) URL(key string) string {
+	return l.baseURL + "/" + strings.TrimLeft(key, "/")
+}
+
+func (l *localFileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,70 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingReader struct{ read bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("connection reset")
+	}
+	r.read = true
+	return copy(p, "partial"), nil
+}
+
+func TestLocalFileStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	fs := NewLocalFileStorage(root, "/assets/")
+
+	if err := fs.Save(ctx, "icons/a.svg", strings.NewReader("<svg/>"), 6, "image/svg+xml"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	f, err := fs.Open(ctx, "icons/a.svg")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "<svg/>" {
+		t.Errorf("Open() read %q", data)
+	}
+	if got := fs.URL("icons/a.svg"); got != "/assets/icons/a.svg" {
+		t.Errorf("URL() = %q", got)
+	}
+
+	if err := fs.Save(ctx, "icons/b.svg", &failingReader{}, 100, "image/svg+xml"); err == nil {
+		t.Error("Save() of a failing reader succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(root, "icons", "b.svg")); !os.IsNotExist(err) {
+		t.Errorf("Save() left a partial file: %v", err)
+	}
+
+	if err := fs.Save(ctx, "../../escape.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.txt")); err != nil {
+		t.Errorf("a key with .. was not kept below the root: %v", err)
+	}
+	if err := fs.Save(ctx, "/", strings.NewReader("x"), 1, "text/plain"); err == nil {
+		t.Error("Save() accepted an empty key")
+	}
+
+	if err := fs.Delete(ctx, "icons/a.svg"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := fs.Delete(ctx, "icons/a.svg"); err != nil {
+		t.Errorf("Delete() of a missing file error = %v", err)
+	}
+	if _, err := fs.Open(ctx, "icons/a.svg"); err == nil {
+		t.Error("Open() found a deleted file")
+	}
+}
@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"spektr-pages-api/domain"
+	"strings"
+)
+
+type Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	UseSSL    bool
+	// PublicURL overrides the address objects are linked from, e.g. a CDN
+	// host. Defaults to <endpoint>/<bucket>.
+	PublicURL string
+}
+
+type s3FileStorage struct {
+	client  *minio.Client
+	bucket  string
+	baseURL string
+}
+
+// NewS3FileStorage connects to any S3 compatible service (AWS, MinIO, ...)
+// and creates the bucket when it is missing.
+func NewS3FileStorage(ctx context.Context, cfg Config) (domain.FileStorage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		err = client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	baseURL := cfg.PublicURL
+	if baseURL == "" {
+		scheme := "http://"
+		if cfg.UseSSL {
+			scheme = "https://"
+		}
+		baseURL = scheme + cfg.Endpoint + "/" + cfg.Bucket
+	}
+	return &s3FileStorage{
+		client:  client,
+		bucket:  cfg.Bucket,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+func (s *s3FileStorage) Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return nil
+}
+
+func (s *s3FileStorage) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return nil
+}
+
+func (s *s3FileStorage) URL(key string) string {
+	return s.baseURL + "/" + strings.TrimLeft(key, "/")
+}
+
+func (s *s3FileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	_, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, domain.ErrNotFound
+		}
+		return nil, domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return obj, nil
+}
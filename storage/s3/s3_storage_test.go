@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"spektr-pages-api/domain"
+	"strings"
+	"testing"
+)
+
+// newTestStorage connects to the S3 service in S3_TEST_ENDPOINT, e.g. the
+// MinIO container of docker-compose ("localhost:9000"), with the credentials
+// in S3_ACCESS_KEY and S3_SECRET_KEY. The test is skipped without it.
+func newTestStorage(t *testing.T) domain.FileStorage {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT is not set")
+	}
+	bucket := os.Getenv("S3_TEST_BUCKET")
+	if bucket == "" {
+		bucket = "spektr-pages-test"
+	}
+	fs, err := NewS3FileStorage(context.Background(), Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+		Bucket:    bucket,
+		PublicURL: "https://cdn.example.com/",
+	})
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", endpoint, err)
+	}
+	return fs
+}
+
+func TestS3FileStorage(t *testing.T) {
+	fs := newTestStorage(t)
+	ctx := context.Background()
+	key := "test/" + t.Name() + ".txt"
+	content := "hello"
+
+	if err := fs.Save(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	r, err := fs.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if string(got) != content {
+		t.Errorf("Open read %q, want %q", got, content)
+	}
+	if url, want := fs.URL(key), "https://cdn.example.com/"+key; url != want {
+		t.Errorf("URL = %q, want %q", url, want)
+	}
+
+	if err := fs.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := fs.Open(ctx, key); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+}
@@ -1,252 +1,676 @@
 package http
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/spf13/viper"
-	"mime/multipart"
+	"io"
 	"net/http"
-	"path/filepath"
 	domain "spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"spektr-pages-api/pagination"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type TariffHandler struct {
 	TUsecase domain.TariffUsecase
+	Storage  domain.FileStorage
 }
 
-func NewTariffHandler(g *gin.Engine, us domain.TariffUsecase) {
+func NewTariffHandler(g *gin.Engine, us domain.TariffUsecase, fs domain.FileStorage, auth gin.HandlerFunc) {
 	handler := &TariffHandler{
 		TUsecase: us,
+		Storage:  fs,
+	}
+	v1 := g.Group("/api/v1")
+	v1.GET("/tariffs", handler.SearchTariffs)
+	v1.GET("/cities/:id/tariffs", handler.GetTariffsByCity)
+	v1.GET("/tariffs/:id", handler.GetTariffByID)
+	v1.POST("/tariffs", auth, handler.AddTariff)
+	v1.PATCH("/tariffs/:id", auth, handler.UpdateTariff)
+	v1.DELETE("/tariffs/:id", auth, handler.DeleteTariff)
+	v1.GET("/trash/tariffs", auth, handler.GetDeletedTariffs)
+	v1.POST("/tariffs/:id/restore", auth, handler.RestoreTariff)
+
+	v1.GET("/types", handler.ListTypes)
+
+	v1.GET("/tariff-types", handler.ListTariffTypes)
+	v1.GET("/tariff-types/:id", handler.GetTariffTypeByID)
+	v1.POST("/tariff-types", auth, handler.AddTariffType)
+	v1.PATCH("/tariff-types/:id", auth, handler.UpdateTariffType)
+	v1.DELETE("/tariff-types/:id", auth, handler.DeleteTariffType)
+
+	v1.GET("/icons", handler.ListIcons)
+	v1.GET("/icons/:id", handler.GetIconByID)
+	v1.POST("/icons", auth, handler.AddIcon)
+	v1.PUT("/icons/:id", auth, handler.ReplaceIcon)
+	v1.DELETE("/icons/:id", auth, handler.DeleteIcon)
+
+	// Routes from before /api/v1, reading ids from JSON bodies. Kept while
+	// clients migrate; disable with server.legacy_routes=false.
+	if !viper.GetBool("server.legacy_routes") {
+		return
 	}
 	g.GET("/tariffs", handler.GetTariff)
 	g.GET("/types", handler.GetType)
 	g.GET("/tariff-types", handler.GetTariffType)
 	g.GET("/icons", handler.GetIcons)
+	g.GET("/tariff/:id", handler.GetTariffByID)
+	g.GET("/tariff-type/:id", handler.GetTariffTypeByID)
+	g.GET("/icon/:id", handler.GetIconByID)
 
-	g.DELETE("/tariff", handler.RemoveTariff)
-	g.DELETE("/tariff-type", handler.RemoveTariffType)
-	g.DELETE("/icon", handler.RemoveIcon)
+	g.DELETE("/tariff", auth, handler.RemoveTariff)
+	g.DELETE("/tariff-type", auth, handler.RemoveTariffType)
+	g.DELETE("/icon", auth, handler.RemoveIcon)
 
-	g.POST("/tariff", handler.AddTariff)
-	g.POST("/tariff-type", handler.AddTariffType)
-	g.POST("/icon", handler.AddIcon)
+	g.POST("/tariff", auth, handler.AddTariff)
+	g.POST("/tariff-type", auth, handler.AddTariffType)
+	g.POST("/icon", auth, handler.AddIcon)
 
+	g.PATCH("/tariff/:id", auth, handler.UpdateTariff)
+	g.PATCH("/tariff-type/:id", auth, handler.UpdateTariffType)
+	g.PUT("/icon/:id", auth, handler.ReplaceIcon)
 }
 
 func (a *TariffHandler) GetTariff(c *gin.Context) {
+	modified := a.lastModified(c)
 	var id domain.Tariff
-	err := c.BindJSON(&id)
+	err := c.ShouldBindJSON(&id)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
 		return
 	}
 	ctx := c.Request.Context()
 
 	Tariffs, err := a.TUsecase.GetTariffs(ctx, id.City)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, map[string]interface{}{
+	a.tariffURLs(Tariffs)
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
 		"result": Tariffs,
 	})
 }
-func (a *TariffHandler) GetType(c *gin.Context) {
+
+func (a *TariffHandler) GetTariffsByCity(c *gin.Context) {
+	modified := a.lastModified(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RenderError(c, domain.ErrBadParamInput)
+		return
+	}
 	ctx := c.Request.Context()
-	Tariffs, err := a.TUsecase.GetTypes(ctx)
+	Tariffs, err := a.TUsecase.GetTariffs(ctx, id)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, map[string]interface{}{
+	a.tariffURLs(Tariffs)
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
 		"result": Tariffs,
 	})
 }
+
+// SearchTariffs serves /tariffs. Every query parameter is optional:
+// city_id, min_price, max_price, period_per_pay, tariff_type_id and type_id
+// (repeated or comma separated), q, sort (id, price or title) and the usual
+// paging parameters.
+func (a *TariffHandler) SearchTariffs(c *gin.Context) {
+	modified := a.lastModified(c)
+	query, err := parseTariffQuery(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	page, err := a.TUsecase.SearchTariffs(ctx, query)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	a.tariffURLs(page.Items)
+	middleware.ConditionalJSON(c, modified, page)
+}
+
+func parseTariffQuery(c *gin.Context) (domain.TariffQuery, error) {
+	var query domain.TariffQuery
+	var err error
+	if v := c.Query("city_id"); v != "" {
+		if query.CityID, err = strconv.Atoi(v); err != nil {
+			return query, domain.ErrBadParamInput
+		}
+	}
+	if query.MinPrice, err = floatQuery(c, "min_price"); err != nil {
+		return query, err
+	}
+	if query.MaxPrice, err = floatQuery(c, "max_price"); err != nil {
+		return query, err
+	}
+	if query.TariffTypeIDs, err = idsQuery(c, "tariff_type_id"); err != nil {
+		return query, err
+	}
+	if query.TypeIDs, err = idsQuery(c, "type_id"); err != nil {
+		return query, err
+	}
+	query.PeriodPerPay = c.Query("period_per_pay")
+	query.Text = strings.TrimSpace(c.Query("q"))
+	query.PageRequest, err = pagination.FromQuery(c)
+	return query, err
+}
+
+func floatQuery(c *gin.Context, name string) (*float64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, domain.ErrBadParamInput
+	}
+	return &f, nil
+}
+
+// idsQuery reads a list of ids given as repeated and/or comma separated
+// query parameters.
+func idsQuery(c *gin.Context, name string) ([]int, error) {
+	var ids []int
+	for _, v := range c.QueryArray(name) {
+		for _, part := range strings.Split(v, ",") {
+			if part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, domain.ErrBadParamInput
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+func (a *TariffHandler) GetType(c *gin.Context) {
+	modified := a.lastModified(c)
+	ctx := c.Request.Context()
+	Tariffs, err := a.TUsecase.GetTypes(ctx, domain.PageRequest{Limit: domain.NoPageLimit})
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
+		"result": Tariffs.Items,
+	})
+}
 func (a *TariffHandler) GetTariffType(c *gin.Context) {
+	modified := a.lastModified(c)
 	ctx := c.Request.Context()
-	Tariffs, err := a.TUsecase.GetTariffTypes(ctx)
+	Tariffs, err := a.TUsecase.GetTariffTypes(ctx, domain.PageRequest{Limit: domain.NoPageLimit})
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"result": Tariffs,
+	a.tariffTypeURLs(Tariffs.Items)
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
+		"result": Tariffs.Items,
+	})
+}
+func (a *TariffHandler) GetTariffByID(c *gin.Context) {
+	modified := a.lastModified(c)
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	tariff, err := a.TUsecase.GetTariff(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	a.tariffTypeURLs(tariff.Types)
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
+		"result": tariff,
+	})
+}
+func (a *TariffHandler) GetTariffTypeByID(c *gin.Context) {
+	modified := a.lastModified(c)
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	tType, err := a.TUsecase.GetTariffType(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	tType.IconPath = domain.AssetURL(a.Storage, tType.IconPath)
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
+		"result": tType,
+	})
+}
+func (a *TariffHandler) GetIconByID(c *gin.Context) {
+	modified := a.lastModified(c)
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	icon, err := a.TUsecase.GetIcon(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	icon = a.iconURLs(icon)
+	middleware.ConditionalJSON(c, modified, map[string]interface{}{
+		"result": icon,
 	})
 }
 func (a *TariffHandler) RemoveTariff(c *gin.Context) {
 	var id domain.Tariff
-	err := c.BindJSON(&id)
+	err := c.ShouldBindJSON(&id)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
 		return
 	}
 	ctx := c.Request.Context()
 	err = a.TUsecase.RemoveTariff(ctx, id.Id)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, "ok")
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
 }
 func (a *TariffHandler) RemoveTariffType(c *gin.Context) {
 	var id domain.TariffType
-	err := c.BindJSON(&id)
+	err := c.ShouldBindJSON(&id)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
 		return
 	}
 	ctx := c.Request.Context()
-	err = a.TUsecase.RemoveTariffType(ctx, id.ID)
+	cascade, err := cascadeParam(c)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, "ok")
+	err = a.TUsecase.RemoveTariffType(ctx, id.ID, cascade)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
 }
 func (a *TariffHandler) AddTariff(c *gin.Context) {
 	var tariff domain.Tariff
-	err := c.BindJSON(&tariff)
+	err := c.ShouldBindJSON(&tariff)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
 		return
 	}
 	ctx := c.Request.Context()
 	err = a.TUsecase.AddTariff(ctx, tariff)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, "ok")
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
 }
 func (a *TariffHandler) AddTariffType(c *gin.Context) {
 	var tariff domain.TariffType
-	err := c.BindJSON(&tariff)
+	err := c.ShouldBindJSON(&tariff)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
 		return
 	}
 	ctx := c.Request.Context()
 	err = a.TUsecase.AddTariffType(ctx, tariff)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, "ok")
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
 }
+
+// AddIcon answers with the stored icon, which is an existing one when the
+// same file was uploaded before.
 func (a *TariffHandler) AddIcon(c *gin.Context) {
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
-		return
-	}
-	icon := saveFile(c, file)
-	icon = viper.GetString("server.address") + "/assets/icons/" + icon
+	upload, file, err := iconUpload(c)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
+	defer file.Close()
 	ctx := c.Request.Context()
-	err = a.TUsecase.AddIcon(ctx, domain.Icon{Path: icon})
+	icon, err := a.TUsecase.AddIcon(ctx, upload)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, "ok")
+	icon = a.iconURLs(icon)
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"result": icon,
+	})
 }
 func (a *TariffHandler) RemoveIcon(c *gin.Context) {
 	var id domain.Icon
-	err := c.BindJSON(&id)
+	err := c.ShouldBindJSON(&id)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
 		return
 	}
 	ctx := c.Request.Context()
-	err = a.TUsecase.RemoveIcon(ctx, id.ID)
+	cascade, err := cascadeParam(c)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
+		return
+	}
+	err = a.TUsecase.RemoveIcon(ctx, id.ID, cascade)
+	if err != nil {
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, "ok")
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
 }
 func (a *TariffHandler) GetIcons(c *gin.Context) {
+	modified := a.lastModified(c)
 	ctx := c.Request.Context()
-	icons, err := a.TUsecase.GetIcons(ctx)
+	icons, err := a.TUsecase.GetIcons(ctx, domain.PageRequest{Limit: domain.NoPageLimit})
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
+		middleware.RenderError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, icons)
+	a.iconListURLs(icons.Items)
+	middleware.ConditionalJSON(c, modified, icons.Items)
 }
-func saveFile(c *gin.Context, file *multipart.FileHeader) string {
-	UUID, err := uuid.NewUUID()
+
+// ListTypes, ListTariffTypes and ListIcons serve the paginated /api/v1
+// lists; GetType, GetTariffType and GetIcons keep the unpaginated legacy
+// responses.
+func (a *TariffHandler) ListTypes(c *gin.Context) {
+	modified := a.lastModified(c)
+	page, err := pagination.FromQuery(c)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
-		})
-		return ""
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	types, err := a.TUsecase.GetTypes(ctx, page)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
 	}
-	extension := filepath.Ext(file.Filename)
-	file.Filename = UUID.String()
+	middleware.ConditionalJSON(c, modified, types)
+}
+func (a *TariffHandler) ListTariffTypes(c *gin.Context) {
+	modified := a.lastModified(c)
+	page, err := pagination.FromQuery(c)
 	if err != nil {
-		c.JSON(getStatusCode(err), map[string]string{
-			"error": err.Error(),
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	types, err := a.TUsecase.GetTariffTypes(ctx, page)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	a.tariffTypeURLs(types.Items)
+	middleware.ConditionalJSON(c, modified, types)
+}
+func (a *TariffHandler) ListIcons(c *gin.Context) {
+	modified := a.lastModified(c)
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	icons, err := a.TUsecase.GetIcons(ctx, page)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	a.iconListURLs(icons.Items)
+	middleware.ConditionalJSON(c, modified, icons)
+}
+
+func (a *TariffHandler) UpdateTariff(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	var patch domain.TariffPatch
+	err = c.ShouldBindJSON(&patch)
+	if err != nil {
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
+		return
+	}
+	ctx := c.Request.Context()
+	err = a.TUsecase.UpdateTariff(ctx, id, patch)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (a *TariffHandler) UpdateTariffType(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	var patch domain.TariffTypePatch
+	err = c.ShouldBindJSON(&patch)
+	if err != nil {
+		middleware.RenderError(c, domain.WrapError(domain.ErrBadParamInput, err))
+		return
+	}
+	ctx := c.Request.Context()
+	err = a.TUsecase.UpdateTariffType(ctx, id, patch)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (a *TariffHandler) ReplaceIcon(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	upload, file, err := iconUpload(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	defer file.Close()
+	ctx := c.Request.Context()
+	icon, err := a.TUsecase.ReplaceIcon(ctx, id, upload)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	icon = a.iconURLs(icon)
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"result": icon,
+	})
+}
+
+func (a *TariffHandler) DeleteTariff(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	err = a.TUsecase.RemoveTariff(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (a *TariffHandler) DeleteTariffType(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	cascade, err := cascadeParam(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	err = a.TUsecase.RemoveTariffType(ctx, id, cascade)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (a *TariffHandler) DeleteIcon(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	cascade, err := cascadeParam(c)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	err = a.TUsecase.RemoveIcon(ctx, id, cascade)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+func (a *TariffHandler) GetDeletedTariffs(c *gin.Context) {
+	ctx := c.Request.Context()
+	tariffs, err := a.TUsecase.GetDeletedTariffs(ctx)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	a.tariffURLs(tariffs)
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"result": tariffs,
+	})
+}
+func (a *TariffHandler) RestoreTariff(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	err = a.TUsecase.RestoreTariff(ctx, id)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+
+// tariffURLs replaces the icon keys of the tariff types of tariffs with
+// their public URLs.
+func (a *TariffHandler) tariffURLs(tariffs []domain.Tariff) {
+	for i := range tariffs {
+		a.tariffTypeURLs(tariffs[i].Types)
+	}
+}
+
+func (a *TariffHandler) tariffTypeURLs(types []domain.TariffType) {
+	for i := range types {
+		types[i].IconPath = domain.AssetURL(a.Storage, types[i].IconPath)
+	}
+}
+
+func (a *TariffHandler) iconListURLs(icons []domain.Icon) {
+	for i := range icons {
+		icons[i] = a.iconURLs(icons[i])
+	}
+}
+
+// iconURLs returns icon with the keys of the icon and its thumbnails
+// replaced by their public URLs.
+func (a *TariffHandler) iconURLs(icon domain.Icon) domain.Icon {
+	icon.Path = domain.AssetURL(a.Storage, icon.Path)
+	if icon.Thumbnails != nil {
+		thumbs := make([]domain.Thumbnail, len(icon.Thumbnails))
+		for i, t := range icon.Thumbnails {
+			thumbs[i] = domain.Thumbnail{Size: t.Size, Path: domain.AssetURL(a.Storage, t.Path)}
+		}
+		icon.Thumbnails = thumbs
+	}
+	return icon
+}
+
+// cascadeParam reads the cascade query parameter of the delete routes.
+func cascadeParam(c *gin.Context) (bool, error) {
+	v := c.Query("cascade")
+	if v == "" {
+		return false, nil
+	}
+	cascade, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, domain.ErrBadParamInput
+	}
+	return cascade, nil
+}
+
+// idParam reads a numeric path parameter.
+func idParam(c *gin.Context, name string) (int, error) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return 0, domain.ErrBadParamInput
+	}
+	return id, nil
+}
+
+// multipartOverhead is how much larger than the icon itself an upload body
+// may be, leaving room for the multipart boundaries and headers.
+const multipartOverhead = 64 << 10
+
+// iconUpload opens the file form field. The caller closes the returned file.
+// The body is cut off past icons.max_size, so an oversized upload is refused
+// before it is parsed and spooled to disk; the usecase checks the exact size.
+func iconUpload(c *gin.Context) (domain.Upload, io.Closer, error) {
+	maxSize := viper.GetInt64("icons.max_size")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return domain.Upload{}, nil, domain.NewError(domain.ErrTooLarge, "the icon is too large").WithDetails(map[string]int64{
+			"max_size": maxSize,
 		})
-		return ""
-	}
-	path := file.Filename + extension
-	err = c.SaveUploadedFile(file, "/spektr-pages-api/static/icons/"+file.Filename+extension)
-	return path
-}
-func getStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
-	}
-	switch err {
-	case domain.ErrInternalServerError:
-		return http.StatusInternalServerError
-	case domain.ErrNotFound:
-		return http.StatusNotFound
-	case domain.ErrConflict:
-		return http.StatusConflict
-	case domain.UserAlreadyExist:
-		return http.StatusConflict
-	default:
-		return http.StatusInternalServerError
 	}
+	if err != nil {
+		return domain.Upload{}, nil, domain.NewError(domain.ErrBadParamInput, "File is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return domain.Upload{}, nil, domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	return domain.Upload{Size: header.Size, Content: file}, file, nil
+}
+
+// lastModified is read before the data so Last-Modified never runs ahead of
+// the body. Without it the response just goes out with the ETag alone.
+func (a *TariffHandler) lastModified(c *gin.Context) time.Time {
+	modified, err := a.TUsecase.LastModified(c.Request.Context())
+	if err != nil {
+		return time.Time{}
+	}
+	return modified
 }
@@ -2,19 +2,29 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
 	"github.com/jmoiron/sqlx"
-	"golang.org/x/sync/errgroup"
+	"github.com/lib/pq"
 	"spektr-pages-api/domain"
+	"spektr-pages-api/pagination"
+	"spektr-pages-api/pgerr"
+	"strings"
+	"time"
 )
 
 type psqlTariffRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	schema string
 }
 
-func NewTariffRepository(conn *sqlx.DB) domain.TariffRepository {
-	return &psqlTariffRepository{conn}
+func NewTariffRepository(conn *sqlx.DB, schema string) domain.TariffRepository {
+	return &psqlTariffRepository{conn, pq.QuoteIdentifier(schema)}
+}
+
+// q qualifies the {schema} placeholders in query with the configured schema.
+func (p *psqlTariffRepository) q(query string) string {
+	return strings.ReplaceAll(query, "{schema}", p.schema)
 }
 
 func jsonToString(i interface{}) string {
@@ -25,18 +35,44 @@ func jsonToString(i interface{}) string {
 	return string(b)
 }
 
-func (p *psqlTariffRepository) GetTypes(ctx context.Context) ([]domain.Type, error) {
+// The sort fields of the list methods mapped to columns.
+var (
+	typeSorts       = map[string]string{"id": "id", "name": "name"}
+	tariffTypeSorts = map[string]string{"id": "tt.id", "name": "tt.name", "title": "tt.title"}
+	iconSorts       = map[string]string{"id": "id"}
+)
+
+func (p *psqlTariffRepository) GetTypes(ctx context.Context, page domain.PageRequest) (domain.Page[domain.Type], error) {
+	offset, err := page.Start()
+	if err != nil {
+		return domain.Page[domain.Type]{}, err
+	}
+	var total int
+	err = p.db.GetContext(ctx, &total, p.q("SELECT count(*) FROM {schema}.t_type"))
+	if err != nil {
+		return domain.Page[domain.Type]{}, pgerr.Map(err)
+	}
 	var types []domain.Type
-	err := p.db.SelectContext(ctx, &types, "SELECT id, name FROM spektr.t_type")
+	query := p.q("SELECT id, name FROM {schema}.t_type ORDER BY ") + pagination.OrderBy(typeSorts, page) + " LIMIT $1 OFFSET $2"
+	err = p.db.SelectContext(ctx, &types, query, pagination.LimitArg(page), offset)
 	if err != nil {
-		return nil, domain.ErrInternalServerError
+		return domain.Page[domain.Type]{}, pgerr.Map(err)
 	}
-	return types, nil
+	return domain.NewPage(types, total, page, offset), nil
 }
 
-func (p *psqlTariffRepository) GetTariffTypes(ctx context.Context) ([]domain.TariffType, error) {
+func (p *psqlTariffRepository) GetTariffTypes(ctx context.Context, page domain.PageRequest) (domain.Page[domain.TariffType], error) {
+	offset, err := page.Start()
+	if err != nil {
+		return domain.Page[domain.TariffType]{}, err
+	}
+	var total int
+	err = p.db.GetContext(ctx, &total, p.q("SELECT count(*) FROM {schema}.t_tariff_type"))
+	if err != nil {
+		return domain.Page[domain.TariffType]{}, pgerr.Map(err)
+	}
 	var types []domain.TariffType
-	query := `
+	query := p.q(`
 		SELECT
 			tt.id,
 			tt.name,
@@ -48,14 +84,16 @@ func (p *psqlTariffRepository) GetTariffTypes(ctx context.Context) ([]domain.Tar
 			ot.name AS typeName,
 			ic.path
 		FROM
-			spektr.t_tariff_type tt
+			{schema}.t_tariff_type tt
 		JOIN
-			t_type ot ON tt.type = ot.id
+			{schema}.t_type ot ON tt.type = ot.id
 		JOIN
-			t_icon ic ON ic.id = tt.icon`
-	rows, err := p.db.QueryxContext(ctx, query)
+			{schema}.t_icon ic ON ic.id = tt.icon
+		ORDER BY `) + pagination.OrderBy(tariffTypeSorts, page) + `
+		LIMIT $1 OFFSET $2`
+	rows, err := p.db.QueryxContext(ctx, query, pagination.LimitArg(page), offset)
 	if err != nil {
-		return nil, domain.ErrInternalServerError
+		return domain.Page[domain.TariffType]{}, pgerr.Map(err)
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -65,24 +103,24 @@ func (p *psqlTariffRepository) GetTariffTypes(ctx context.Context) ([]domain.Tar
 			&tt.ID, &tt.Name, &descriptionJSON, &tt.Title, &tt.Subtitle, &tt.Icon,
 			&tt.Type, &tt.TypeName, &tt.IconPath,
 		); err != nil {
-			return nil, domain.ErrInternalServerError
+			return domain.Page[domain.TariffType]{}, pgerr.Map(err)
 		}
 		var descriptions []domain.Description
 		if err := json.Unmarshal(descriptionJSON, &descriptions); err != nil {
-			return nil, domain.ErrInternalServerError
+			return domain.Page[domain.TariffType]{}, pgerr.Map(err)
 		}
 		tt.Description = descriptions
 		types = append(types, tt)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, domain.ErrInternalServerError
+		return domain.Page[domain.TariffType]{}, pgerr.Map(err)
 	}
-	return types, nil
+	return domain.NewPage(types, total, page, offset), nil
 }
 
-func (p *psqlTariffRepository) getTariffTypesById(ctx context.Context, id int) ([]domain.TariffType, error) {
-	var types []domain.TariffType
-	query := `
+func (p *psqlTariffRepository) GetTariffType(ctx context.Context, id int) (domain.TariffType, error) {
+	var tt domain.TariffType
+	query := p.q(`
 		SELECT
 			tt.id,
 			tt.name,
@@ -94,192 +132,733 @@ func (p *psqlTariffRepository) getTariffTypesById(ctx context.Context, id int) (
 			ot.name AS typeName,
 			ic.path
 		FROM
-			spektr.t_tariff_type tt
+			{schema}.t_tariff_type tt
+		JOIN
+			{schema}.t_type ot ON tt.type = ot.id
 		JOIN
-			t_type ot ON tt.type = ot.id
+			{schema}.t_icon ic ON ic.id = tt.icon
+		WHERE
+			tt.id = $1`)
+	var descriptionJSON []byte
+	err := p.db.QueryRowxContext(ctx, query, id).Scan(
+		&tt.ID, &tt.Name, &descriptionJSON, &tt.Title, &tt.Subtitle, &tt.Icon,
+		&tt.Type, &tt.TypeName, &tt.IconPath,
+	)
+	if err == sql.ErrNoRows {
+		return domain.TariffType{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.TariffType{}, pgerr.Map(err)
+	}
+	if err := json.Unmarshal(descriptionJSON, &tt.Description); err != nil {
+		return domain.TariffType{}, pgerr.Map(err)
+	}
+	return tt, nil
+}
+
+// liveCondition matches tariffs that are published and inside their
+// publishing window, mirroring domain.Tariff.Live.
+const liveCondition = `t.status = 'published'
+	AND (t.publish_at IS NULL OR t.publish_at <= now())
+	AND (t.unpublish_at IS NULL OR t.unpublish_at > now())`
+
+func (p *psqlTariffRepository) GetTariffs(ctx context.Context, id int, liveOnly bool) ([]domain.Tariff, error) {
+	var tariffs []domain.Tariff
+	query := p.q(`
+		SELECT
+			t.id, t.price, t.period_per_pay, t.title, t.subtitle, t.short_description,
+			t.status, t.publish_at, t.unpublish_at
+		FROM
+			{schema}.t_tariff t
 		JOIN
-			t_icon ic ON ic.id = tt.icon
+			{schema}.t_city_tariff ct ON t.id = ct.tariff_id
 		JOIN
-			spektr.t_tariff_type_tariff tttt ON tt.id = tttt.tariff_type_id
+			{schema}.t_city c ON c.id = ct.city_id
 		WHERE
-			tttt.tariff_id = $1`
-	rows, err := p.db.QueryxContext(ctx, query, id)
+			ct.city_id = $1 AND t.deleted_at IS NULL AND c.deleted_at IS NULL
+			AND (NOT $2::boolean OR (` + liveCondition + `))`)
+	err := p.db.SelectContext(ctx, &tariffs, query, id, liveOnly)
 	if err != nil {
-		return nil, err
+		return nil, pgerr.Map(err)
+	}
+	if err := p.fillTypes(ctx, tariffs); err != nil {
+		return nil, pgerr.Map(err)
+	}
+	if err := p.fillCities(ctx, tariffs); err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return tariffs, nil
+}
+
+// fillTypes loads the tariff types of all tariffs with a single query and
+// assigns them in Go.
+func (p *psqlTariffRepository) fillTypes(ctx context.Context, tariffs []domain.Tariff) error {
+	if len(tariffs) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(tariffs))
+	for _, t := range tariffs {
+		ids = append(ids, int64(t.Id))
+	}
+	query := p.q(`
+		SELECT
+			tttt.tariff_id,
+			tt.id,
+			tt.name,
+			tt.description,
+			tt.title,
+			tt.subtitle,
+			tt.icon,
+			tt.type,
+			ot.name AS typeName,
+			ic.path
+		FROM
+			{schema}.t_tariff_type tt
+		JOIN
+			{schema}.t_type ot ON tt.type = ot.id
+		JOIN
+			{schema}.t_icon ic ON ic.id = tt.icon
+		JOIN
+			{schema}.t_tariff_type_tariff tttt ON tt.id = tttt.tariff_type_id
+		WHERE
+			tttt.tariff_id = ANY($1)
+		ORDER BY
+			tt.id`)
+	rows, err := p.db.QueryxContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
 	}
 	defer rows.Close()
+	types := map[int][]domain.TariffType{}
 	for rows.Next() {
+		var tariffID int
 		var tt domain.TariffType
 		var descriptionJSON []byte
 		if err := rows.Scan(
-			&tt.ID, &tt.Name, &descriptionJSON, &tt.Title, &tt.Subtitle, &tt.Icon,
+			&tariffID, &tt.ID, &tt.Name, &descriptionJSON, &tt.Title, &tt.Subtitle, &tt.Icon,
 			&tt.Type, &tt.TypeName, &tt.IconPath,
 		); err != nil {
-			return nil, err
+			return err
 		}
-		var descriptions []domain.Description
-		if err := json.Unmarshal(descriptionJSON, &descriptions); err != nil {
-			return nil, err
+		if err := json.Unmarshal(descriptionJSON, &tt.Description); err != nil {
+			return err
 		}
-		tt.Description = descriptions
-		types = append(types, tt)
+		types[tariffID] = append(types[tariffID], tt)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
-	return types, nil
+	for i := range tariffs {
+		tariffs[i].Types = types[tariffs[i].Id]
+	}
+	return nil
 }
 
-func (p *psqlTariffRepository) GetTariffs(ctx context.Context, id int) ([]domain.Tariff, error) {
-	var tariffs []domain.Tariff
-	err := p.db.SelectContext(ctx, &tariffs, "SELECT id, price, period_per_pay, title, subtitle, short_description FROM spektr.t_tariff join t_city_tariff on t_tariff.id = t_city_tariff.tariff_id where city_id = $1;", id)
+func (p *psqlTariffRepository) GetTariff(ctx context.Context, id int) (domain.Tariff, error) {
+	var tariff domain.Tariff
+	query := p.q(`
+		SELECT
+			t.id, t.price, t.period_per_pay, t.title, t.subtitle, t.short_description,
+			t.status, t.publish_at, t.unpublish_at
+		FROM
+			{schema}.t_tariff t
+		WHERE
+			t.id = $1 AND t.deleted_at IS NULL`)
+	err := p.db.GetContext(ctx, &tariff, query, id)
+	if err == sql.ErrNoRows {
+		return domain.Tariff{}, domain.ErrNotFound
+	}
 	if err != nil {
-		return nil, domain.ErrInternalServerError
+		return domain.Tariff{}, pgerr.Map(err)
 	}
-	var eg errgroup.Group
-	for i := range tariffs {
-		v := &tariffs[i]
-		eg.Go(func() error {
-			tariffTypes, err := p.getTariffTypesById(ctx, v.Id)
-			if err != nil {
-				return err
-			}
-			v.Types = tariffTypes
-			return nil
-		})
-	}
-	if err := eg.Wait(); err != nil {
-		return nil, domain.ErrInternalServerError
-	}
-	fmt.Println()
-	return tariffs, nil
+	tariffs := []domain.Tariff{tariff}
+	if err := p.fillTypes(ctx, tariffs); err != nil {
+		return domain.Tariff{}, pgerr.Map(err)
+	}
+	if err := p.fillCities(ctx, tariffs); err != nil {
+		return domain.Tariff{}, pgerr.Map(err)
+	}
+	return tariffs[0], nil
+}
+
+// iconColumns are selected for every domain.Icon; hash is NULL for icons
+// stored before hashing.
+const iconColumns = "id, path, content_type, COALESCE(hash, '') AS hash"
+
+func (p *psqlTariffRepository) GetIcon(ctx context.Context, id int) (domain.Icon, error) {
+	return p.getIcon(ctx, p.db, "id = $1", id)
+}
+
+func (p *psqlTariffRepository) GetIconByHash(ctx context.Context, hash string) (domain.Icon, error) {
+	return p.getIcon(ctx, p.db, "hash = $1", hash)
+}
+
+func (p *psqlTariffRepository) getIcon(ctx context.Context, q sqlx.QueryerContext, where string, arg interface{}) (domain.Icon, error) {
+	var icon domain.Icon
+	err := sqlx.GetContext(ctx, q, &icon, p.q("SELECT "+iconColumns+" FROM {schema}.t_icon WHERE "+where), arg)
+	if err == sql.ErrNoRows {
+		return domain.Icon{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.Icon{}, pgerr.Map(err)
+	}
+	icons := []domain.Icon{icon}
+	if err := p.fillThumbnails(ctx, q, icons); err != nil {
+		return domain.Icon{}, pgerr.Map(err)
+	}
+	return icons[0], nil
+}
+
+// fillThumbnails loads the thumbnails of icons in one query.
+func (p *psqlTariffRepository) fillThumbnails(ctx context.Context, q sqlx.QueryerContext, icons []domain.Icon) error {
+	if len(icons) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(icons))
+	for _, icon := range icons {
+		ids = append(ids, int64(icon.ID))
+	}
+	var rows []struct {
+		IconID int `db:"icon_id"`
+		domain.Thumbnail
+	}
+	err := sqlx.SelectContext(ctx, q, &rows, p.q(`SELECT icon_id, size, path FROM {schema}.t_icon_thumbnail WHERE icon_id = ANY($1) ORDER BY size`), pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	thumbnails := map[int][]domain.Thumbnail{}
+	for _, row := range rows {
+		thumbnails[row.IconID] = append(thumbnails[row.IconID], row.Thumbnail)
+	}
+	for i := range icons {
+		icons[i].Thumbnails = thumbnails[icons[i].ID]
+		if icons[i].Thumbnails == nil {
+			icons[i].Thumbnails = []domain.Thumbnail{}
+		}
+	}
+	return nil
 }
 
-func (p *psqlTariffRepository) GetIcons(ctx context.Context) ([]domain.Icon, error) {
+// insertThumbnails links the thumbnails of icon to it.
+func (p *psqlTariffRepository) insertThumbnails(ctx context.Context, tx *sqlx.Tx, icon domain.Icon) error {
+	if len(icon.Thumbnails) == 0 {
+		return nil
+	}
+	sizes := make([]int64, 0, len(icon.Thumbnails))
+	paths := make([]string, 0, len(icon.Thumbnails))
+	for _, t := range icon.Thumbnails {
+		sizes = append(sizes, int64(t.Size))
+		paths = append(paths, t.Path)
+	}
+	query := p.q(`INSERT INTO {schema}.t_icon_thumbnail (icon_id, size, path) SELECT $1, * FROM unnest($2::int[], $3::text[])`)
+	_, err := tx.ExecContext(ctx, query, icon.ID, pq.Array(sizes), pq.Array(paths))
+	return err
+}
+
+func (p *psqlTariffRepository) GetIcons(ctx context.Context, page domain.PageRequest) (domain.Page[domain.Icon], error) {
+	offset, err := page.Start()
+	if err != nil {
+		return domain.Page[domain.Icon]{}, err
+	}
+	var total int
+	err = p.db.GetContext(ctx, &total, p.q("SELECT count(*) FROM {schema}.t_icon"))
+	if err != nil {
+		return domain.Page[domain.Icon]{}, pgerr.Map(err)
+	}
 	var icons []domain.Icon
-	err := p.db.SelectContext(ctx, &icons, "SELECT id, path FROM spektr.t_icon")
+	query := p.q("SELECT "+iconColumns+" FROM {schema}.t_icon ORDER BY ") + pagination.OrderBy(iconSorts, page) + " LIMIT $1 OFFSET $2"
+	err = p.db.SelectContext(ctx, &icons, query, pagination.LimitArg(page), offset)
 	if err != nil {
-		return nil, domain.ErrInternalServerError
+		return domain.Page[domain.Icon]{}, pgerr.Map(err)
+	}
+	if err := p.fillThumbnails(ctx, p.db, icons); err != nil {
+		return domain.Page[domain.Icon]{}, pgerr.Map(err)
 	}
-	return icons, nil
+	return domain.NewPage(icons, total, page, offset), nil
 }
 
-func (p *psqlTariffRepository) AddTariffType(ctx context.Context, tType domain.TariffType) error {
-	query := `INSERT INTO t_tariff_type (name, description, title, subtitle, icon, type) VALUES ($1, $2, $3, $4, $5, $6);`
+func (p *psqlTariffRepository) AddTariffType(ctx context.Context, tType domain.TariffType) (int, error) {
+	query := p.q(`INSERT INTO {schema}.t_tariff_type (name, description, title, subtitle, icon, type) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`)
 	stmt, err := p.db.PrepareContext(ctx, query)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return 0, pgerr.Map(err)
 	}
 	defer stmt.Close() // Close the statement after use
-	_, err = stmt.ExecContext(ctx, tType.Name, jsonToString(tType.Description), tType.Title, tType.Subtitle, tType.Icon, tType.Type)
+	var insertedID int
+	err = stmt.QueryRowContext(ctx, tType.Name, jsonToString(tType.Description), tType.Title, tType.Subtitle, tType.Icon, tType.Type).Scan(&insertedID)
+	if err != nil {
+		return 0, pgerr.Map(err)
+	}
+	return insertedID, nil
+}
+
+// RemoveTariffType deletes the tariff type. Without cascade it fails while
+// tariffs, trashed ones included, are linked to it; with cascade the links
+// are dropped along with it.
+func (p *psqlTariffRepository) RemoveTariffType(ctx context.Context, id int, cascade bool) (domain.Cascade, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	defer tx.Rollback()
+
+	// The row lock holds off new links until the delete commits.
+	err = tx.GetContext(ctx, new(int), p.q(`SELECT id FROM {schema}.t_tariff_type WHERE id = $1 FOR UPDATE`), id)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	var cascaded domain.Cascade
+	if cascade {
+		// ON DELETE CASCADE would drop the links too, but they are deleted
+		// here so they can be returned.
+		err = tx.SelectContext(ctx, &cascaded.TariffTypeLinks, p.q(`
+			DELETE FROM {schema}.t_tariff_type_tariff WHERE tariff_type_id = $1
+			RETURNING tariff_type_id, tariff_id`), id)
+		if err != nil {
+			return domain.Cascade{}, pgerr.Map(err)
+		}
+	} else {
+		dependants, err := selectDependants(ctx, tx, domain.EntityTariff, p.q(`
+			SELECT t.id, t.title AS name
+			FROM {schema}.t_tariff_type_tariff tttt
+			JOIN {schema}.t_tariff t ON t.id = tttt.tariff_id
+			WHERE tttt.tariff_type_id = $1
+			ORDER BY t.id`), id)
+		if err != nil {
+			return domain.Cascade{}, err
+		}
+		if len(dependants) > 0 {
+			return domain.Cascade{}, domain.DependantsError(dependants)
+		}
+	}
+	_, err = tx.ExecContext(ctx, p.q(`DELETE FROM {schema}.t_tariff_type WHERE id = $1`), id)
+	if err != nil {
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Cascade{}, pgerr.Map(err)
+	}
+	return cascaded, nil
+}
+
+// RemoveTariff moves the tariff to the trash. Its city and tariff type links
+// are kept so RestoreTariff brings it back unchanged.
+func (p *psqlTariffRepository) RemoveTariff(ctx context.Context, id int) error {
+	query := p.q(`UPDATE {schema}.t_tariff SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`)
+	res, err := p.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
 	}
 	return nil
 }
 
-func (p *psqlTariffRepository) RemoveTariffType(ctx context.Context, id int) error {
-	query := `DELETE FROM spektr.t_tariff_type WHERE id = $1`
-	stmt, err := p.db.PrepareContext(ctx, query)
+func (p *psqlTariffRepository) RestoreTariff(ctx context.Context, id int) error {
+	query := p.q(`UPDATE {schema}.t_tariff SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`)
+	res, err := p.db.ExecContext(ctx, query, id)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
 	}
-	defer stmt.Close() // Close the statement after use
-	_, err = stmt.ExecContext(ctx, id)
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
 	}
 	return nil
 }
 
-func (p *psqlTariffRepository) RemoveTariff(ctx context.Context, id int) error {
-	query := `DELETE FROM t_tariff WHERE id = $1`
-	stmt, err := p.db.PrepareContext(ctx, query)
+func (p *psqlTariffRepository) GetDeletedTariffs(ctx context.Context) ([]domain.Tariff, error) {
+	var tariffs []domain.Tariff
+	query := p.q(`
+		SELECT
+			t.id, t.price, t.period_per_pay, t.title, t.subtitle, t.short_description,
+			t.status, t.publish_at, t.unpublish_at, t.deleted_at
+		FROM
+			{schema}.t_tariff t
+		WHERE
+			t.deleted_at IS NOT NULL
+		ORDER BY
+			t.deleted_at DESC`)
+	err := p.db.SelectContext(ctx, &tariffs, query)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return nil, pgerr.Map(err)
 	}
-	defer stmt.Close() // Close the statement after use
-	_, err = stmt.ExecContext(ctx, id)
+	if err := p.fillCities(ctx, tariffs); err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return tariffs, nil
+}
+
+// fillCities sets Cities and City on each tariff with a single query over
+// all of them. Cities in the trash are left out.
+func (p *psqlTariffRepository) fillCities(ctx context.Context, tariffs []domain.Tariff) error {
+	if len(tariffs) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(tariffs))
+	for _, t := range tariffs {
+		ids = append(ids, int64(t.Id))
+	}
+	query := p.q(`
+		SELECT
+			ct.tariff_id, ct.city_id
+		FROM
+			{schema}.t_city_tariff ct
+		JOIN
+			{schema}.t_city c ON c.id = ct.city_id
+		WHERE
+			ct.tariff_id = ANY($1) AND c.deleted_at IS NULL
+		ORDER BY
+			ct.city_id`)
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
-		return domain.ErrInternalServerError
+		return err
+	}
+	defer rows.Close()
+	cities := map[int][]int{}
+	for rows.Next() {
+		var tariffID, cityID int
+		if err := rows.Scan(&tariffID, &cityID); err != nil {
+			return err
+		}
+		cities[tariffID] = append(cities[tariffID], cityID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range tariffs {
+		tariffs[i].Cities = cities[tariffs[i].Id]
+		if tariffs[i].Cities == nil {
+			tariffs[i].Cities = []int{}
+			continue
+		}
+		tariffs[i].City = tariffs[i].Cities[0]
 	}
 	return nil
 }
 
-func (p *psqlTariffRepository) AddTariff(ctx context.Context, tariff domain.Tariff) error {
-	query := `INSERT INTO spektr.t_tariff (price, period_per_pay, title, subtitle, short_description) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	stmt, err := p.db.PrepareContext(ctx, query)
+// tariffCities merges the legacy City field into Cities.
+func tariffCities(tariff domain.Tariff) []int64 {
+	ids := make([]int64, 0, len(tariff.Cities)+1)
+	seen := map[int]bool{}
+	for _, id := range append([]int{tariff.City}, tariff.Cities...) {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, int64(id))
+	}
+	return ids
+}
+
+func (p *psqlTariffRepository) AddTariff(ctx context.Context, tariff domain.Tariff) (int, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return 0, pgerr.Map(err)
 	}
-	defer stmt.Close() // Close the statement after use
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := p.q(`INSERT INTO {schema}.t_tariff (price, period_per_pay, title, subtitle, short_description, status, publish_at, unpublish_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`)
 	var insertedID int
-	err = stmt.QueryRowContext(ctx, tariff.Price, tariff.PeriodPerPay, tariff.Title, tariff.Subtitle, tariff.ShortDescription).Scan(&insertedID)
-	if err != nil {
-		return domain.ErrInternalServerError
-	}
-	query = `INSERT INTO spektr.t_tariff_type_tariff (tariff_id, tariff_type_id) VALUES ($1, $2)`
-	var g errgroup.Group
-	for _, v := range tariff.Types {
-		v := v
-		g.Go(func() error {
-			stmt, err := p.db.PrepareContext(ctx, query)
-			if err != nil {
-				return domain.ErrInternalServerError
-			}
-			defer stmt.Close() // Close the statement after use
-			_, err = stmt.ExecContext(ctx, insertedID, v.ID)
-			if err != nil {
-				return domain.ErrInternalServerError
-			}
-			return nil
-		})
-	}
-	if err := g.Wait(); err != nil {
-		return err
+	err = tx.QueryRowContext(ctx, query, tariff.Price, tariff.PeriodPerPay, tariff.Title, tariff.Subtitle, tariff.ShortDescription, tariff.Status, tariff.PublishAt, tariff.UnpublishAt).Scan(&insertedID)
+	if err != nil {
+		return 0, pgerr.Map(err)
 	}
-	cityTariffQuery := `INSERT INTO spektr.t_city_tariff (city_id, tariff_id) VALUES ($1, $2)`
-	stmt, err = p.db.PrepareContext(ctx, cityTariffQuery)
+	if len(tariff.Types) > 0 {
+		typeIDs := make([]int64, 0, len(tariff.Types))
+		for _, v := range tariff.Types {
+			typeIDs = append(typeIDs, int64(v.ID))
+		}
+		query = p.q(`INSERT INTO {schema}.t_tariff_type_tariff (tariff_id, tariff_type_id) SELECT $1, unnest($2::int[])`)
+		_, err = tx.ExecContext(ctx, query, insertedID, pq.Array(typeIDs))
+		if err != nil {
+			return 0, pgerr.Map(err)
+		}
+	}
+	cityTariffQuery := p.q(`INSERT INTO {schema}.t_city_tariff (city_id, tariff_id) SELECT unnest($2::int[]), $1`)
+	_, err = tx.ExecContext(ctx, cityTariffQuery, insertedID, pq.Array(tariffCities(tariff)))
 	if err != nil {
-		return domain.ErrInternalServerError
+		return 0, pgerr.Map(err)
 	}
-	defer stmt.Close() // Close the statement after use
-	_, err = stmt.ExecContext(ctx, tariff.City, insertedID)
+	if err := tx.Commit(); err != nil {
+		return 0, pgerr.Map(err)
+	}
+	return insertedID, nil
+}
+
+// AddIcon is ErrConflict when an icon with the same hash exists.
+func (p *psqlTariffRepository) AddIcon(ctx context.Context, icon domain.Icon) (int, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, pgerr.Map(err)
+	}
+	defer tx.Rollback()
+
+	query := p.q(`INSERT INTO {schema}.t_icon (path, content_type, hash) VALUES ($1, $2, NULLIF($3, '')) RETURNING id`)
+	err = tx.QueryRowContext(ctx, query, icon.Path, icon.ContentType, icon.Hash).Scan(&icon.ID)
+	if err != nil {
+		return 0, pgerr.Map(err)
+	}
+	if err := p.insertThumbnails(ctx, tx, icon); err != nil {
+		return 0, pgerr.Map(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, pgerr.Map(err)
+	}
+	return icon.ID, nil
+}
+
+// RemoveIcon deletes the icon. Without cascade it fails while tariff types
+// use it; with cascade those tariff types are deleted too, since a tariff
+// type cannot be left without an icon.
+func (p *psqlTariffRepository) RemoveIcon(ctx context.Context, id int, cascade bool) (domain.Icon, domain.Cascade, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Icon{}, domain.Cascade{}, pgerr.Map(err)
+	}
+	defer tx.Rollback()
+
+	// The row lock holds off new tariff types using the icon until the
+	// delete commits.
+	icon, err := p.getIcon(ctx, tx, "id = $1 FOR UPDATE", id)
+	if err != nil {
+		return domain.Icon{}, domain.Cascade{}, err
+	}
+	var cascaded domain.Cascade
+	if cascade {
+		err = tx.SelectContext(ctx, &cascaded.TariffTypeLinks, p.q(`
+			DELETE FROM {schema}.t_tariff_type_tariff
+			WHERE tariff_type_id IN (SELECT id FROM {schema}.t_tariff_type WHERE icon = $1)
+			RETURNING tariff_type_id, tariff_id`), id)
+		if err != nil {
+			return domain.Icon{}, domain.Cascade{}, pgerr.Map(err)
+		}
+		cascaded.TariffTypes, err = p.deleteIconTariffTypes(ctx, tx, id)
+		if err != nil {
+			return domain.Icon{}, domain.Cascade{}, err
+		}
+	} else {
+		dependants, err := selectDependants(ctx, tx, domain.EntityTariffType, p.q(`
+			SELECT id, name FROM {schema}.t_tariff_type WHERE icon = $1 ORDER BY id`), id)
+		if err != nil {
+			return domain.Icon{}, domain.Cascade{}, err
+		}
+		if len(dependants) > 0 {
+			return domain.Icon{}, domain.Cascade{}, domain.DependantsError(dependants)
+		}
+	}
+	_, err = tx.ExecContext(ctx, p.q(`DELETE FROM {schema}.t_icon WHERE id = $1`), id)
+	if err != nil {
+		return domain.Icon{}, domain.Cascade{}, pgerr.Map(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Icon{}, domain.Cascade{}, pgerr.Map(err)
+	}
+	return icon, cascaded, nil
+}
+
+// deleteIconTariffTypes deletes the tariff types using the icon and returns
+// them as they were.
+func (p *psqlTariffRepository) deleteIconTariffTypes(ctx context.Context, tx *sqlx.Tx, iconID int) ([]domain.TariffType, error) {
+	rows, err := tx.QueryxContext(ctx, p.q(`
+		DELETE FROM {schema}.t_tariff_type tt
+		USING {schema}.t_type ot, {schema}.t_icon ic
+		WHERE tt.icon = $1 AND ot.id = tt.type AND ic.id = tt.icon
+		RETURNING tt.id, tt.name, tt.description, tt.title, tt.subtitle, tt.icon, tt.type, ot.name, ic.path`), iconID)
+	if err != nil {
+		return nil, pgerr.Map(err)
+	}
+	defer rows.Close()
+	var types []domain.TariffType
+	for rows.Next() {
+		var tt domain.TariffType
+		var descriptionJSON []byte
+		err := rows.Scan(
+			&tt.ID, &tt.Name, &descriptionJSON, &tt.Title, &tt.Subtitle, &tt.Icon,
+			&tt.Type, &tt.TypeName, &tt.IconPath,
+		)
+		if err != nil {
+			return nil, pgerr.Map(err)
+		}
+		if err := json.Unmarshal(descriptionJSON, &tt.Description); err != nil {
+			return nil, domain.WrapError(domain.ErrInternalServerError, err)
+		}
+		types = append(types, tt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return types, nil
+}
+
+// selectDependants runs query, which selects id and name, and tags the rows
+// with entity.
+func selectDependants(ctx context.Context, tx *sqlx.Tx, entity, query string, args ...interface{}) ([]domain.Dependant, error) {
+	var dependants []domain.Dependant
+	if err := tx.SelectContext(ctx, &dependants, query, args...); err != nil {
+		return nil, pgerr.Map(err)
+	}
+	for i := range dependants {
+		dependants[i].Entity = entity
+	}
+	return dependants, nil
+}
+
+func (p *psqlTariffRepository) UpdateTariff(ctx context.Context, id int, patch domain.TariffPatch) error {
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
+	}
+	defer tx.Rollback()
+
+	query := p.q(`
+		UPDATE {schema}.t_tariff SET
+			price = COALESCE($2, price),
+			period_per_pay = COALESCE($3, period_per_pay),
+			title = COALESCE($4, title),
+			subtitle = COALESCE($5, subtitle),
+			short_description = COALESCE($6, short_description),
+			status = COALESCE($7, status),
+			publish_at = CASE WHEN $10 THEN NULL ELSE COALESCE($8, publish_at) END,
+			unpublish_at = CASE WHEN $11 THEN NULL ELSE COALESCE($9, unpublish_at) END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING status, publish_at, unpublish_at`)
+	var merged domain.Tariff
+	err = tx.GetContext(ctx, &merged, query, id, patch.Price, patch.PeriodPerPay, patch.Title, patch.Subtitle, patch.ShortDescription, patch.Status, patch.PublishAt, patch.UnpublishAt, patch.ClearPublishAt, patch.ClearUnpublishAt)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	// The schedule is checked on the merged row, since the patch alone does
+	// not show the dates it leaves unchanged.
+	if err := merged.CheckSchedule(); err != nil {
+		return err
+	}
+	if patch.Types != nil {
+		_, err = tx.ExecContext(ctx, p.q(`DELETE FROM {schema}.t_tariff_type_tariff WHERE tariff_id = $1`), id)
+		if err != nil {
+			return pgerr.Map(err)
+		}
+		typeIDs := make([]int64, 0, len(*patch.Types))
+		for _, v := range *patch.Types {
+			typeIDs = append(typeIDs, int64(v.ID))
+		}
+		_, err = tx.ExecContext(ctx, p.q(`INSERT INTO {schema}.t_tariff_type_tariff (tariff_id, tariff_type_id) SELECT $1, unnest($2::int[])`), id, pq.Array(typeIDs))
+		if err != nil {
+			return pgerr.Map(err)
+		}
+	}
+	if patch.Cities != nil {
+		_, err = tx.ExecContext(ctx, p.q(`DELETE FROM {schema}.t_city_tariff WHERE tariff_id = $1`), id)
+		if err != nil {
+			return pgerr.Map(err)
+		}
+		cityIDs := tariffCities(domain.Tariff{Cities: *patch.Cities})
+		_, err = tx.ExecContext(ctx, p.q(`INSERT INTO {schema}.t_city_tariff (city_id, tariff_id) SELECT unnest($2::int[]), $1`), id, pq.Array(cityIDs))
+		if err != nil {
+			return pgerr.Map(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return pgerr.Map(err)
 	}
 	return nil
 }
 
-func (p *psqlTariffRepository) AddIcon(ctx context.Context, icon domain.Icon) error {
-	query := `INSERT INTO spektr.t_icon (path) VALUES ($1)`
-	stmt, err := p.db.PrepareContext(ctx, query)
+func (p *psqlTariffRepository) UpdateTariffType(ctx context.Context, id int, patch domain.TariffTypePatch) error {
+	var description interface{}
+	if patch.Description != nil {
+		description = jsonToString(*patch.Description)
+	}
+	query := p.q(`
+		UPDATE {schema}.t_tariff_type SET
+			name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			title = COALESCE($4, title),
+			subtitle = COALESCE($5, subtitle),
+			icon = COALESCE($6, icon),
+			type = COALESCE($7, type)
+		WHERE id = $1`)
+	res, err := p.db.ExecContext(ctx, query, id, patch.Name, description, patch.Title, patch.Subtitle, patch.Icon, patch.Type)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
 	}
-	defer stmt.Close() // Close the statement after use
-	_, err = stmt.ExecContext(ctx, icon.Path)
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return domain.ErrInternalServerError
+		return pgerr.Map(err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
 	}
 	return nil
 }
 
-func (p *psqlTariffRepository) RemoveIcon(ctx context.Context, id int) (string, error) {
-	var path string
-	err := p.db.GetContext(ctx, &path, "SELECT path FROM spektr.t_icon WHERE id =$1", id)
+func (p *psqlTariffRepository) ReplaceIcon(ctx context.Context, icon domain.Icon) (domain.Icon, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return "", domain.ErrInternalServerError
+		return domain.Icon{}, pgerr.Map(err)
 	}
-	query := `DELETE FROM t_icon WHERE id = $1`
-	stmt, err := p.db.PrepareContext(ctx, query)
+	defer tx.Rollback()
+
+	old, err := p.getIcon(ctx, tx, "id = $1 FOR UPDATE", icon.ID)
 	if err != nil {
-		return "", domain.ErrInternalServerError
+		return domain.Icon{}, err
 	}
-	defer stmt.Close() // Close the statement after use
-	_, err = stmt.ExecContext(ctx, id)
+	query := p.q("UPDATE {schema}.t_icon SET path = $2, content_type = $3, hash = NULLIF($4, '') WHERE id = $1")
+	_, err = tx.ExecContext(ctx, query, icon.ID, icon.Path, icon.ContentType, icon.Hash)
+	if err != nil {
+		return domain.Icon{}, pgerr.Map(err)
+	}
+	_, err = tx.ExecContext(ctx, p.q("DELETE FROM {schema}.t_icon_thumbnail WHERE icon_id = $1"), icon.ID)
+	if err != nil {
+		return domain.Icon{}, pgerr.Map(err)
+	}
+	if err := p.insertThumbnails(ctx, tx, icon); err != nil {
+		return domain.Icon{}, pgerr.Map(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Icon{}, pgerr.Map(err)
+	}
+	return old, nil
+}
+
+func (p *psqlTariffRepository) PublishDue(ctx context.Context) ([]domain.Tariff, error) {
+	query := p.q(`
+		UPDATE {schema}.t_tariff t SET
+			status = CASE WHEN due.status = 'scheduled' THEN 'published' ELSE 'archived' END
+		FROM (
+			SELECT id, status FROM {schema}.t_tariff
+			WHERE
+				deleted_at IS NULL
+				AND ((status = 'scheduled' AND publish_at <= now() AND (unpublish_at IS NULL OR unpublish_at > now()))
+					OR (status IN ('scheduled', 'published') AND unpublish_at <= now()))
+			FOR UPDATE
+		) due
+		WHERE t.id = due.id
+		RETURNING t.id, due.status`)
+	var changed []domain.Tariff
+	err := p.db.SelectContext(ctx, &changed, query)
+	if err != nil {
+		return nil, pgerr.Map(err)
+	}
+	return changed, nil
+}
+
+// catalogTables are the tables tariff reads depend on.
+var catalogTables = []string{"t_city", "t_type", "t_icon", "t_tariff_type", "t_tariff", "t_city_tariff", "t_tariff_type_tariff", "t_icon_thumbnail"}
+
+func (p *psqlTariffRepository) LastModified(ctx context.Context) (time.Time, error) {
+	var modified time.Time
+	err := p.db.GetContext(ctx, &modified, p.q("SELECT max(updated_at) FROM {schema}.t_table_change WHERE table_name = ANY($1)"), pq.Array(catalogTables))
 	if err != nil {
-		return "", domain.ErrInternalServerError
+		return time.Time{}, pgerr.Map(err)
 	}
-	return path, nil
+	return modified, nil
 }
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/pgerr"
+	"strings"
+)
+
+// searchCursor is the position after the last tariff of a page: its sort
+// key and id. It is sent to clients as base64 JSON.
+type searchCursor struct {
+	Price float64 `json:"p,omitempty"`
+	Title string  `json:"t,omitempty"`
+	ID    int     `json:"id"`
+}
+
+func encodeCursor(c searchCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, domain.ErrBadParamInput
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, domain.ErrBadParamInput
+	}
+	return c, nil
+}
+
+// searchBuilder collects WHERE conditions and their positional arguments.
+type searchBuilder struct {
+	where []string
+	args  []interface{}
+}
+
+// arg adds v to the arguments and returns its placeholder.
+func (b *searchBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *searchBuilder) add(cond string) {
+	b.where = append(b.where, cond)
+}
+
+func (b *searchBuilder) clause() string {
+	return strings.Join(b.where, " AND ")
+}
+
+// likeEscaper escapes the LIKE wildcards in user supplied search text.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (p *psqlTariffRepository) SearchTariffs(ctx context.Context, query domain.TariffQuery) (domain.Page[domain.Tariff], error) {
+	b := &searchBuilder{}
+	b.add("t.deleted_at IS NULL")
+	if query.LiveOnly {
+		b.add("(" + liveCondition + ")")
+	}
+	if query.CityID != 0 {
+		b.add(p.q(`EXISTS (
+			SELECT 1 FROM {schema}.t_city_tariff ct JOIN {schema}.t_city c ON c.id = ct.city_id
+			WHERE ct.tariff_id = t.id AND c.deleted_at IS NULL AND ct.city_id = ` + b.arg(query.CityID) + `)`))
+	}
+	if query.MinPrice != nil {
+		b.add("t.price >= " + b.arg(*query.MinPrice))
+	}
+	if query.MaxPrice != nil {
+		b.add("t.price <= " + b.arg(*query.MaxPrice))
+	}
+	if query.PeriodPerPay != "" {
+		b.add("t.period_per_pay = " + b.arg(query.PeriodPerPay))
+	}
+	if len(query.TariffTypeIDs) > 0 {
+		b.add(p.q(`EXISTS (
+			SELECT 1 FROM {schema}.t_tariff_type_tariff tttt
+			WHERE tttt.tariff_id = t.id AND tttt.tariff_type_id = ANY(` + b.arg(pq.Array(query.TariffTypeIDs)) + `::int[]))`))
+	}
+	if len(query.TypeIDs) > 0 {
+		b.add(p.q(`EXISTS (
+			SELECT 1 FROM {schema}.t_tariff_type_tariff tttt JOIN {schema}.t_tariff_type tt ON tt.id = tttt.tariff_type_id
+			WHERE tttt.tariff_id = t.id AND tt.type = ANY(` + b.arg(pq.Array(query.TypeIDs)) + `::int[]))`))
+	}
+	if query.Text != "" {
+		pattern := b.arg("%" + likeEscaper.Replace(query.Text) + "%")
+		b.add("(t.title ILIKE " + pattern + " OR t.subtitle ILIKE " + pattern + " OR t.short_description ILIKE " + pattern + ")")
+	}
+
+	var total int
+	err := p.db.GetContext(ctx, &total, p.q("SELECT count(*) FROM {schema}.t_tariff t WHERE ")+b.clause(), b.args...)
+	if err != nil {
+		return domain.Page[domain.Tariff]{}, pgerr.Map(err)
+	}
+
+	// Keyset pagination: order by the sort column with id as tie-breaker
+	// and continue strictly after the cursor row.
+	column := "t.id"
+	switch query.Sort {
+	case domain.TariffSortPrice:
+		column = "t.price"
+	case domain.TariffSortTitle:
+		column = "t.title"
+	}
+	direction, cmp := "ASC", ">"
+	if query.Desc {
+		direction, cmp = "DESC", "<"
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return domain.Page[domain.Tariff]{}, err
+		}
+		switch query.Sort {
+		case domain.TariffSortPrice:
+			b.add("(t.price, t.id) " + cmp + " (" + b.arg(cursor.Price) + "::numeric, " + b.arg(cursor.ID) + ")")
+		case domain.TariffSortTitle:
+			b.add("(t.title, t.id) " + cmp + " (" + b.arg(cursor.Title) + ", " + b.arg(cursor.ID) + ")")
+		default:
+			b.add("t.id " + cmp + " " + b.arg(cursor.ID))
+		}
+	}
+	order := column + " " + direction
+	if column != "t.id" {
+		order += ", t.id " + direction
+	}
+
+	var tariffs []domain.Tariff
+	selectQuery := p.q(`
+		SELECT
+			t.id, t.price, t.period_per_pay, t.title, t.subtitle, t.short_description,
+			t.status, t.publish_at, t.unpublish_at
+		FROM
+			{schema}.t_tariff t
+		WHERE `) + b.clause() + `
+		ORDER BY ` + order + `
+		LIMIT ` + b.arg(query.Limit+1)
+	if query.Cursor == "" && query.Offset > 0 {
+		selectQuery += " OFFSET " + b.arg(query.Offset)
+	}
+	err = p.db.SelectContext(ctx, &tariffs, selectQuery, b.args...)
+	if err != nil {
+		return domain.Page[domain.Tariff]{}, pgerr.Map(err)
+	}
+
+	page := domain.Page[domain.Tariff]{Total: total}
+	// The next cursor continues after the last row, also for a first page
+	// requested by offset.
+	if len(tariffs) > query.Limit {
+		tariffs = tariffs[:query.Limit]
+		last := tariffs[len(tariffs)-1]
+		page.NextCursor = encodeCursor(searchCursor{Price: last.Price, Title: last.Title, ID: last.Id})
+	}
+	if err := p.fillTypes(ctx, tariffs); err != nil {
+		return domain.Page[domain.Tariff]{}, pgerr.Map(err)
+	}
+	if err := p.fillCities(ctx, tariffs); err != nil {
+		return domain.Page[domain.Tariff]{}, pgerr.Map(err)
+	}
+	if tariffs == nil {
+		tariffs = []domain.Tariff{}
+	}
+	page.Items = tariffs
+	return page, nil
+}
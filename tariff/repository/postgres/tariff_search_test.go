@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, c := range []searchCursor{
+		{ID: 1},
+		{Price: 499.9, ID: 42},
+		{Title: "Домашний 100 & \"TV\"", ID: 7},
+	} {
+		encoded := encodeCursor(c)
+		got, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeCursor(%q) error = %v", encoded, err)
+		}
+		if got != c {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", c, got)
+		}
+	}
+}
+
+func TestDecodeTamperedCursor(t *testing.T) {
+	valid := encodeCursor(searchCursor{Price: 10, ID: 3})
+	for name, cursor := range map[string]string{
+		"not base64":       "not a cursor!",
+		"padded base64":    base64.URLEncoding.EncodeToString([]byte(`{"id":3}`)),
+		"truncated":        valid[:len(valid)-2],
+		"not JSON":         base64.RawURLEncoding.EncodeToString([]byte("id=3")),
+		"id of wrong type": base64.RawURLEncoding.EncodeToString([]byte(`{"id":"3"}`)),
+		"price as text":    base64.RawURLEncoding.EncodeToString([]byte(`{"p":"0 OR 1=1","id":3}`)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeCursor(cursor)
+			if !errors.Is(err, domain.ErrBadParamInput) {
+				t.Fatalf("decodeCursor() error = %v, want %v", err, domain.ErrBadParamInput)
+			}
+			if status := middleware.ErrorStatus(err); status != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestLikeEscaper(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "100%", want: `100\%`},
+		{in: "fast_net", want: `fast\_net`},
+		{in: `C:\net`, want: `C:\\net`},
+		{in: `\%_`, want: `\\\%\_`},
+		{in: "plain text", want: "plain text"},
+	}
+	for _, tt := range tests {
+		if got := likeEscaper.Replace(tt.in); got != tt.want {
+			t.Errorf("likeEscaper.Replace(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
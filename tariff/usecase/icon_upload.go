@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/imaging"
+)
+
+// IconOptions limits icon uploads. MaxSize is in bytes, MaxDimension in
+// pixels per side of raster icons. Raster icons get a thumbnail for each of
+// ThumbnailSizes they are larger than.
+type IconOptions struct {
+	MaxSize        int64
+	MaxDimension   int
+	ThumbnailSizes []int
+}
+
+// iconFile is an upload that passed the checks, ready to be stored.
+type iconFile struct {
+	data        []byte
+	contentType string
+	hash        string
+}
+
+// readIcon reads upload within the size limit, sniffs its type and
+// sanitizes SVGs. The hash is taken before sanitizing so the same upload
+// always finds the icon it produced.
+func (t TariffUsecase) readIcon(upload domain.Upload) (iconFile, error) {
+	tooLarge := domain.NewError(domain.ErrTooLarge, "the icon is too large").WithDetails(map[string]int64{
+		"max_size": t.icons.MaxSize,
+	})
+	if upload.Size > t.icons.MaxSize {
+		return iconFile{}, tooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(upload.Content, t.icons.MaxSize+1))
+	if err != nil {
+		return iconFile{}, domain.WrapError(domain.ErrBadParamInput, err)
+	}
+	if int64(len(data)) > t.icons.MaxSize {
+		return iconFile{}, tooLarge
+	}
+	sum := sha256.Sum256(data)
+	file := iconFile{hash: hex.EncodeToString(sum[:])}
+	file.contentType, err = imaging.Sniff(data, imaging.SVG, imaging.PNG, imaging.WebP)
+	if err != nil {
+		return iconFile{}, err
+	}
+	if file.contentType == imaging.SVG {
+		data, err = imaging.SanitizeSVG(data)
+	} else {
+		err = imaging.CheckRaster(data, t.icons.MaxDimension)
+	}
+	if err != nil {
+		return iconFile{}, err
+	}
+	file.data = data
+	return file, nil
+}
+
+// storeIcon saves file and its thumbnails under keys derived from the hash,
+// so the same content always lands on the same keys.
+func (t TariffUsecase) storeIcon(ctx context.Context, file iconFile) (domain.Icon, error) {
+	var thumbs []imaging.Thumb
+	if file.contentType != imaging.SVG {
+		var err error
+		thumbs, err = imaging.Thumbnails(file.data, t.icons.ThumbnailSizes)
+		if err != nil {
+			return domain.Icon{}, err
+		}
+	}
+	key := "icons/" + file.hash + imaging.Ext[file.contentType]
+	err := t.storage.Save(ctx, key, bytes.NewReader(file.data), int64(len(file.data)), file.contentType)
+	if err != nil {
+		return domain.Icon{}, err
+	}
+	icon := domain.Icon{
+		Path:        key,
+		ContentType: file.contentType,
+		Hash:        file.hash,
+		Thumbnails:  []domain.Thumbnail{},
+	}
+	for _, thumb := range thumbs {
+		key := fmt.Sprintf("icons/%s_%d.png", file.hash, thumb.Size)
+		err := t.storage.Save(ctx, key, bytes.NewReader(thumb.Data), int64(len(thumb.Data)), imaging.PNG)
+		if err != nil {
+			t.deleteIconFiles(ctx, icon)
+			return domain.Icon{}, err
+		}
+		icon.Thumbnails = append(icon.Thumbnails, domain.Thumbnail{Size: thumb.Size, Path: key})
+	}
+	return icon, nil
+}
+
+// deleteIconFiles deletes the file and thumbnails of icon and returns the
+// first error.
+func (t TariffUsecase) deleteIconFiles(ctx context.Context, icon domain.Icon) error {
+	keys := []string{icon.Path}
+	for _, thumb := range icon.Thumbnails {
+		keys = append(keys, thumb.Path)
+	}
+	var first error
+	for _, key := range keys {
+		if err := t.storage.Delete(ctx, key); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
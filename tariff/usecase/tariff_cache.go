@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"spektr-pages-api/cache"
+	"spektr-pages-api/domain"
+	"time"
+)
+
+// cachedTariffUsecase serves the public reads of a TariffUsecase from a
+// cache and drops the catalog from it on every write. Editors always read
+// through, since they see unpublished tariffs and expect their changes
+// immediately.
+type cachedTariffUsecase struct {
+	domain.TariffUsecase
+	cache domain.Cache
+	ttl   time.Duration
+}
+
+func NewCachedTariffUsecase(next domain.TariffUsecase, c domain.Cache, ttl time.Duration) domain.TariffUsecase {
+	return &cachedTariffUsecase{
+		TariffUsecase: next,
+		cache:         c,
+		ttl:           ttl,
+	}
+}
+
+func (t *cachedTariffUsecase) GetTypes(ctx context.Context, page domain.PageRequest) (domain.Page[domain.Type], error) {
+	return cache.Remember(ctx, t.cache, cache.Key("types", page), t.ttl, func() (domain.Page[domain.Type], error) {
+		return t.TariffUsecase.GetTypes(ctx, page)
+	})
+}
+
+func (t *cachedTariffUsecase) GetTariffTypes(ctx context.Context, page domain.PageRequest) (domain.Page[domain.TariffType], error) {
+	return cache.Remember(ctx, t.cache, cache.Key("tariff-types", page), t.ttl, func() (domain.Page[domain.TariffType], error) {
+		return t.TariffUsecase.GetTariffTypes(ctx, page)
+	})
+}
+
+func (t *cachedTariffUsecase) GetTariffType(ctx context.Context, id int) (domain.TariffType, error) {
+	return cache.Remember(ctx, t.cache, cache.Key("tariff-type", id), t.ttl, func() (domain.TariffType, error) {
+		return t.TariffUsecase.GetTariffType(ctx, id)
+	})
+}
+
+func (t *cachedTariffUsecase) GetIcons(ctx context.Context, page domain.PageRequest) (domain.Page[domain.Icon], error) {
+	return cache.Remember(ctx, t.cache, cache.Key("icons", page), t.ttl, func() (domain.Page[domain.Icon], error) {
+		return t.TariffUsecase.GetIcons(ctx, page)
+	})
+}
+
+func (t *cachedTariffUsecase) GetIcon(ctx context.Context, id int) (domain.Icon, error) {
+	return cache.Remember(ctx, t.cache, cache.Key("icon", id), t.ttl, func() (domain.Icon, error) {
+		return t.TariffUsecase.GetIcon(ctx, id)
+	})
+}
+
+func (t *cachedTariffUsecase) GetTariffs(ctx context.Context, id int) ([]domain.Tariff, error) {
+	if canSeeUnpublished(ctx) {
+		return t.TariffUsecase.GetTariffs(ctx, id)
+	}
+	return cache.Remember(ctx, t.cache, cache.Key("tariffs", id), t.ttl, func() ([]domain.Tariff, error) {
+		return t.TariffUsecase.GetTariffs(ctx, id)
+	})
+}
+
+func (t *cachedTariffUsecase) GetTariff(ctx context.Context, id int) (domain.Tariff, error) {
+	if canSeeUnpublished(ctx) {
+		return t.TariffUsecase.GetTariff(ctx, id)
+	}
+	return cache.Remember(ctx, t.cache, cache.Key("tariff", id), t.ttl, func() (domain.Tariff, error) {
+		return t.TariffUsecase.GetTariff(ctx, id)
+	})
+}
+
+func (t *cachedTariffUsecase) SearchTariffs(ctx context.Context, query domain.TariffQuery) (domain.Page[domain.Tariff], error) {
+	if canSeeUnpublished(ctx) {
+		return t.TariffUsecase.SearchTariffs(ctx, query)
+	}
+	return cache.Remember(ctx, t.cache, cache.Key("tariff-search", query), t.ttl, func() (domain.Page[domain.Tariff], error) {
+		return t.TariffUsecase.SearchTariffs(ctx, query)
+	})
+}
+
+// invalidate drops the catalog after a write, whether or not it succeeded;
+// a failed write may still have changed something.
+func (t *cachedTariffUsecase) invalidate(ctx context.Context) {
+	t.cache.DeletePrefix(ctx, cache.CatalogPrefix)
+}
+
+func (t *cachedTariffUsecase) AddTariffType(ctx context.Context, tType domain.TariffType) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.AddTariffType(ctx, tType)
+}
+
+func (t *cachedTariffUsecase) RemoveTariffType(ctx context.Context, id int, cascade bool) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.RemoveTariffType(ctx, id, cascade)
+}
+
+func (t *cachedTariffUsecase) RemoveTariff(ctx context.Context, id int) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.RemoveTariff(ctx, id)
+}
+
+func (t *cachedTariffUsecase) AddTariff(ctx context.Context, tariff domain.Tariff) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.AddTariff(ctx, tariff)
+}
+
+func (t *cachedTariffUsecase) AddIcon(ctx context.Context, upload domain.Upload) (domain.Icon, error) {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.AddIcon(ctx, upload)
+}
+
+func (t *cachedTariffUsecase) RemoveIcon(ctx context.Context, id int, cascade bool) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.RemoveIcon(ctx, id, cascade)
+}
+
+func (t *cachedTariffUsecase) UpdateTariff(ctx context.Context, id int, patch domain.TariffPatch) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.UpdateTariff(ctx, id, patch)
+}
+
+func (t *cachedTariffUsecase) UpdateTariffType(ctx context.Context, id int, patch domain.TariffTypePatch) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.UpdateTariffType(ctx, id, patch)
+}
+
+func (t *cachedTariffUsecase) ReplaceIcon(ctx context.Context, id int, upload domain.Upload) (domain.Icon, error) {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.ReplaceIcon(ctx, id, upload)
+}
+
+func (t *cachedTariffUsecase) RestoreTariff(ctx context.Context, id int) error {
+	defer t.invalidate(ctx)
+	return t.TariffUsecase.RestoreTariff(ctx, id)
+}
+
+func (t *cachedTariffUsecase) PublishScheduled(ctx context.Context) ([]int, error) {
+	ids, err := t.TariffUsecase.PublishScheduled(ctx)
+	if len(ids) > 0 {
+		t.invalidate(ctx)
+	}
+	return ids, err
+}
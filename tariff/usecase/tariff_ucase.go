@@ -2,36 +2,45 @@ package usecase
 
 import (
 	"context"
-	"fmt"
-	"os"
+	"errors"
+	"log"
 	"spektr-pages-api/domain"
-	"strings"
+	"spektr-pages-api/validation"
 	"time"
 )
 
 type TariffUsecase struct {
 	tariffRepo     domain.TariffRepository
+	storage        domain.FileStorage
+	audit          domain.AuditUsecase
+	icons          IconOptions
 	contextTimeout time.Duration
 }
 
-func (t TariffUsecase) GetTypes(ctx context.Context) ([]domain.Type, error) {
+func (t TariffUsecase) GetTypes(ctx context.Context, page domain.PageRequest) (domain.Page[domain.Type], error) {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	types, err := t.tariffRepo.GetTypes(ctx)
+	if err := page.Normalize("id", "name"); err != nil {
+		return domain.Page[domain.Type]{}, err
+	}
+	types, err := t.tariffRepo.GetTypes(ctx, page)
 	if err != nil {
-		return []domain.Type{}, err
+		return domain.Page[domain.Type]{}, err
 	}
 	return types, nil
 }
 
-func (t TariffUsecase) GetTariffTypes(ctx context.Context) ([]domain.TariffType, error) {
+func (t TariffUsecase) GetTariffTypes(ctx context.Context, page domain.PageRequest) (domain.Page[domain.TariffType], error) {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	types, err := t.tariffRepo.GetTariffTypes(ctx)
+	if err := page.Normalize("id", "name", "title"); err != nil {
+		return domain.Page[domain.TariffType]{}, err
+	}
+	types, err := t.tariffRepo.GetTariffTypes(ctx, page)
 	if err != nil {
-		return []domain.TariffType{}, err
+		return domain.Page[domain.TariffType]{}, err
 	}
 	return types, nil
 }
@@ -40,43 +49,90 @@ func (t TariffUsecase) GetTariffs(ctx context.Context, id int) ([]domain.Tariff,
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	types, err := t.tariffRepo.GetTariffs(ctx, id)
+	types, err := t.tariffRepo.GetTariffs(ctx, id, !canSeeUnpublished(ctx))
 	if err != nil {
 		return []domain.Tariff{}, err
 	}
 	return types, nil
 }
 
-func (t TariffUsecase) GetIcons(ctx context.Context) ([]domain.Icon, error) {
+func (t TariffUsecase) GetIcons(ctx context.Context, page domain.PageRequest) (domain.Page[domain.Icon], error) {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	types, err := t.tariffRepo.GetIcons(ctx)
+	if err := page.Normalize("id"); err != nil {
+		return domain.Page[domain.Icon]{}, err
+	}
+	icons, err := t.tariffRepo.GetIcons(ctx, page)
 	if err != nil {
-		return []domain.Icon{}, err
+		return domain.Page[domain.Icon]{}, err
 	}
-	return types, nil
+	return icons, nil
+}
+
+func (t TariffUsecase) GetTariff(ctx context.Context, id int) (domain.Tariff, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	tariff, err := t.tariffRepo.GetTariff(ctx, id)
+	if err != nil {
+		return domain.Tariff{}, err
+	}
+	if !canSeeUnpublished(ctx) && !tariff.Live(time.Now()) {
+		return domain.Tariff{}, domain.ErrNotFound
+	}
+	return tariff, nil
+}
+
+func (t TariffUsecase) GetTariffType(ctx context.Context, id int) (domain.TariffType, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	tType, err := t.tariffRepo.GetTariffType(ctx, id)
+	if err != nil {
+		return domain.TariffType{}, err
+	}
+	return tType, nil
+}
+
+func (t TariffUsecase) GetIcon(ctx context.Context, id int) (domain.Icon, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	icon, err := t.tariffRepo.GetIcon(ctx, id)
+	if err != nil {
+		return domain.Icon{}, err
+	}
+	return icon, nil
 }
 
 func (t TariffUsecase) AddTariffType(ctx context.Context, tType domain.TariffType) error {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	err := t.tariffRepo.AddTariffType(ctx, tType)
+	if err := validation.Struct(tType); err != nil {
+		return err
+	}
+
+	id, err := t.tariffRepo.AddTariffType(ctx, tType)
 	if err != nil {
 		return err
 	}
+	t.audit.Record(ctx, domain.AuditCreate, domain.EntityTariffType, id, nil, t.tariffTypeSnapshot(ctx, id))
 	return nil
 }
 
-func (t TariffUsecase) RemoveTariffType(ctx context.Context, id int) error {
+func (t TariffUsecase) RemoveTariffType(ctx context.Context, id int, cascade bool) error {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	err := t.RemoveTariffType(ctx, id)
+	before := t.tariffTypeSnapshot(ctx, id)
+	cascaded, err := t.tariffRepo.RemoveTariffType(ctx, id, cascade)
 	if err != nil {
 		return err
 	}
+	t.recordCascade(ctx, cascaded)
+	t.audit.Record(ctx, domain.AuditDelete, domain.EntityTariffType, id, before, nil)
 	return nil
 }
 
@@ -84,10 +140,12 @@ func (t TariffUsecase) RemoveTariff(ctx context.Context, Id int) error {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
+	before := t.tariffSnapshot(ctx, Id)
 	err := t.tariffRepo.RemoveTariff(ctx, Id)
 	if err != nil {
 		return err
 	}
+	t.audit.Record(ctx, domain.AuditDelete, domain.EntityTariff, Id, before, nil)
 	return nil
 }
 
@@ -95,43 +153,268 @@ func (t TariffUsecase) AddTariff(ctx context.Context, tariff domain.Tariff) erro
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	err := t.tariffRepo.AddTariff(ctx, tariff)
+	if err := validation.Struct(tariff); err != nil {
+		return err
+	}
+	if err := tariff.CheckCities(); err != nil {
+		return err
+	}
+	if err := tariff.CheckSchedule(); err != nil {
+		return err
+	}
+	if tariff.Status == "" {
+		tariff.Status = domain.TariffDraft
+	}
+	id, err := t.tariffRepo.AddTariff(ctx, tariff)
+	if err != nil {
+		return err
+	}
+	t.audit.Record(ctx, domain.AuditCreate, domain.EntityTariff, id, nil, t.tariffSnapshot(ctx, id))
+	return nil
+}
+
+func (t TariffUsecase) AddIcon(ctx context.Context, upload domain.Upload) (domain.Icon, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	file, err := t.readIcon(upload)
+	if err != nil {
+		return domain.Icon{}, err
+	}
+	existing, err := t.tariffRepo.GetIconByHash(ctx, file.hash)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return domain.Icon{}, err
+	}
+	icon, err := t.storeIcon(ctx, file)
+	if err != nil {
+		return domain.Icon{}, err
+	}
+	icon.ID, err = t.tariffRepo.AddIcon(ctx, icon)
+	if errors.Is(err, domain.ErrConflict) {
+		// A concurrent upload of the same content got in first. The files
+		// just saved are its files too, so they stay.
+		return t.tariffRepo.GetIconByHash(ctx, file.hash)
+	}
+	if err != nil {
+		t.deleteIconFiles(ctx, icon)
+		return domain.Icon{}, err
+	}
+	t.audit.Record(ctx, domain.AuditCreate, domain.EntityIcon, icon.ID, nil, icon)
+	return icon, nil
+}
+
+func (t TariffUsecase) RemoveIcon(ctx context.Context, id int, cascade bool) error {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	icon, cascaded, err := t.tariffRepo.RemoveIcon(ctx, id, cascade)
 	if err != nil {
 		return err
 	}
+	t.recordCascade(ctx, cascaded)
+	t.audit.Record(ctx, domain.AuditDelete, domain.EntityIcon, id, icon, nil)
+	// The icon is already gone, so a leftover file is only logged.
+	if err := t.deleteIconFiles(ctx, icon); err != nil {
+		log.Printf("icons: removing files of icon %d: %v", id, err)
+	}
 	return nil
 }
 
-func (t TariffUsecase) AddIcon(ctx context.Context, icon domain.Icon) error {
+func (t TariffUsecase) UpdateTariff(ctx context.Context, id int, patch domain.TariffPatch) error {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
-	err := t.tariffRepo.AddIcon(ctx, icon)
+
+	if err := validation.Struct(patch); err != nil {
+		return err
+	}
+	if err := patch.CheckCities(); err != nil {
+		return err
+	}
+	before := t.tariffSnapshot(ctx, id)
+	err := t.tariffRepo.UpdateTariff(ctx, id, patch)
 	if err != nil {
 		return err
 	}
+	t.audit.Record(ctx, domain.AuditUpdate, domain.EntityTariff, id, before, t.tariffSnapshot(ctx, id))
 	return nil
 }
 
-func (t TariffUsecase) RemoveIcon(ctx context.Context, id int) error {
+func (t TariffUsecase) UpdateTariffType(ctx context.Context, id int, patch domain.TariffTypePatch) error {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
 
-	path, err := t.tariffRepo.RemoveIcon(ctx, id)
+	if err := validation.Struct(patch); err != nil {
+		return err
+	}
+
+	before := t.tariffTypeSnapshot(ctx, id)
+	err := t.tariffRepo.UpdateTariffType(ctx, id, patch)
 	if err != nil {
 		return err
 	}
-	splited := strings.Split(path, "/")
-	err = os.Remove("./static/icons/" + splited[len(splited)-1])
+	t.audit.Record(ctx, domain.AuditUpdate, domain.EntityTariffType, id, before, t.tariffTypeSnapshot(ctx, id))
+	return nil
+}
+
+func (t TariffUsecase) ReplaceIcon(ctx context.Context, id int, upload domain.Upload) (domain.Icon, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	file, err := t.readIcon(upload)
+	if err != nil {
+		return domain.Icon{}, err
+	}
+	existing, err := t.tariffRepo.GetIconByHash(ctx, file.hash)
+	switch {
+	case err == nil && existing.ID == id:
+		return existing, nil
+	case err == nil:
+		return domain.Icon{}, domain.NewError(domain.ErrConflict, "another icon has the same content").WithDetails(existing)
+	case !errors.Is(err, domain.ErrNotFound):
+		return domain.Icon{}, err
+	}
+	icon, err := t.storeIcon(ctx, file)
+	if err != nil {
+		return domain.Icon{}, err
+	}
+	icon.ID = id
+	old, err := t.tariffRepo.ReplaceIcon(ctx, icon)
+	if err != nil {
+		// On a conflict the files belong to the icon that won.
+		if !errors.Is(err, domain.ErrConflict) {
+			t.deleteIconFiles(ctx, icon)
+		}
+		return domain.Icon{}, err
+	}
+	t.audit.Record(ctx, domain.AuditUpdate, domain.EntityIcon, id, old, icon)
+	// The icon has already changed, so a leftover file is only logged.
+	if err := t.deleteIconFiles(ctx, old); err != nil {
+		log.Printf("icons: removing old files of icon %d: %v", id, err)
+	}
+	return icon, nil
+}
+
+func (t TariffUsecase) GetDeletedTariffs(ctx context.Context) ([]domain.Tariff, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	tariffs, err := t.tariffRepo.GetDeletedTariffs(ctx)
+	if err != nil {
+		return []domain.Tariff{}, err
+	}
+	return tariffs, nil
+}
+
+func (t TariffUsecase) RestoreTariff(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	err := t.tariffRepo.RestoreTariff(ctx, id)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
+	t.audit.Record(ctx, domain.AuditRestore, domain.EntityTariff, id, nil, t.tariffSnapshot(ctx, id))
 	return nil
 }
 
-func NewTariffUsecase(a domain.TariffRepository, timeout time.Duration) domain.TariffUsecase {
+func (t TariffUsecase) SearchTariffs(ctx context.Context, query domain.TariffQuery) (domain.Page[domain.Tariff], error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	if err := query.Normalize(domain.TariffSortID, domain.TariffSortPrice, domain.TariffSortTitle); err != nil || query.Limit == domain.NoPageLimit {
+		return domain.Page[domain.Tariff]{}, domain.ErrBadParamInput
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return domain.Page[domain.Tariff]{}, domain.ErrBadParamInput
+	}
+	query.LiveOnly = !canSeeUnpublished(ctx)
+
+	page, err := t.tariffRepo.SearchTariffs(ctx, query)
+	if err != nil {
+		return domain.Page[domain.Tariff]{}, err
+	}
+	return page, nil
+}
+
+func (t TariffUsecase) PublishScheduled(ctx context.Context) ([]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	changed, err := t.tariffRepo.PublishDue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(changed))
+	for _, old := range changed {
+		ids = append(ids, old.Id)
+		after, err := t.tariffRepo.GetTariff(ctx, old.Id)
+		if err != nil {
+			log.Printf("audit: loading tariff %d: %v", old.Id, err)
+			continue
+		}
+		// Only the status changed, so the rest of the tariff before is as it
+		// is now.
+		before := after
+		before.Status = old.Status
+		t.audit.Record(ctx, domain.AuditUpdate, domain.EntityTariff, old.Id, before, after)
+	}
+	return ids, nil
+}
+
+func (t TariffUsecase) LastModified(ctx context.Context) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	return t.tariffRepo.LastModified(ctx)
+}
+
+// recordCascade audits each row a delete with cascade removed.
+func (t TariffUsecase) recordCascade(ctx context.Context, cascaded domain.Cascade) {
+	for _, l := range cascaded.TariffTypeLinks {
+		t.audit.Record(ctx, domain.AuditDelete, domain.EntityTariffTypeTariff, l.Tariff, l, nil)
+	}
+	for _, tType := range cascaded.TariffTypes {
+		t.audit.Record(ctx, domain.AuditDelete, domain.EntityTariffType, tType.ID, tType, nil)
+	}
+}
+
+// canSeeUnpublished reports whether the caller may read draft, scheduled and
+// archived tariffs. Anonymous clients and viewers only get live ones.
+func canSeeUnpublished(ctx context.Context) bool {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	return ok && principal.Role.Allows(domain.RoleEditor)
+}
+
+// The snapshot helpers load an entity for the audit log. A failed lookup is
+// logged and returns nil, which Record does not write an entry for; it never
+// fails the change itself.
+func (t TariffUsecase) tariffSnapshot(ctx context.Context, id int) interface{} {
+	tariff, err := t.tariffRepo.GetTariff(ctx, id)
+	if err != nil {
+		log.Printf("audit: loading tariff %d: %v", id, err)
+		return nil
+	}
+	return tariff
+}
+
+func (t TariffUsecase) tariffTypeSnapshot(ctx context.Context, id int) interface{} {
+	tType, err := t.tariffRepo.GetTariffType(ctx, id)
+	if err != nil {
+		log.Printf("audit: loading tariff type %d: %v", id, err)
+		return nil
+	}
+	return tType
+}
+
+func NewTariffUsecase(a domain.TariffRepository, fs domain.FileStorage, audit domain.AuditUsecase, icons IconOptions, timeout time.Duration) domain.TariffUsecase {
 	return &TariffUsecase{
 		tariffRepo:     a,
+		storage:        fs,
+		audit:          audit,
+		icons:          icons,
 		contextTimeout: timeout,
 	}
 }
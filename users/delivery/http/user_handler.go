@@ -0,0 +1,117 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	domain "spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"strconv"
+	"time"
+)
+
+type UserHandler struct {
+	UUsecase domain.UserUsecase
+	Auth     *middleware.AuthMiddleware
+}
+
+func NewUserHandler(g *gin.Engine, us domain.UserUsecase, auth *middleware.AuthMiddleware) {
+	handler := &UserHandler{
+		UUsecase: us,
+		Auth:     auth,
+	}
+
+	v1 := g.Group("/api/v1")
+	v1.POST("/login", handler.Login)
+	v1.POST("/logout", auth.RequireRole(domain.RoleViewer), handler.Logout)
+	v1.POST("/users", auth.RequireRole(domain.RoleAdmin), handler.Register)
+	v1.PUT("/users/me/password", auth.RequireRole(domain.RoleViewer), handler.ChangePassword)
+}
+
+func (h *UserHandler) Register(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req domain.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid user data"))
+		return
+	}
+
+	user, err := h.UUsecase.Register(ctx, req)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"result": user,
+	})
+}
+
+func (h *UserHandler) Login(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req domain.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid login data"))
+		return
+	}
+
+	user, session, err := h.UUsecase.Login(ctx, req)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	token, err := h.Auth.IssueToken(domain.Principal{
+		Subject:   strconv.Itoa(user.ID),
+		Role:      user.Role,
+		SessionID: session.ID,
+	}, session.ExpiresAt)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_at": session.ExpiresAt.Format(time.RFC3339),
+		"user":       user,
+	})
+}
+
+func (h *UserHandler) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	principal, _ := middleware.GetPrincipal(c)
+	if principal.SessionID == "" {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Not a login session"))
+		return
+	}
+
+	err := h.UUsecase.Logout(ctx, principal.SessionID)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
+
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	principal, _ := middleware.GetPrincipal(c)
+	userID, err := strconv.Atoi(principal.Subject)
+	if err != nil || principal.SessionID == "" {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Not a login session"))
+		return
+	}
+	var req domain.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RenderError(c, domain.NewError(domain.ErrBadParamInput, "Invalid password data"))
+		return
+	}
+
+	err = h.UUsecase.ChangePassword(ctx, userID, req)
+	if err != nil {
+		middleware.RenderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": "ok"})
+}
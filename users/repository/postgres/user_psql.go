@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/pgerr"
+	"strings"
+)
+
+type psqlUserRepository struct {
+	db     *sqlx.DB
+	schema string
+}
+
+func NewUserRepository(conn *sqlx.DB, schema string) domain.UserRepository {
+	return &psqlUserRepository{conn, pq.QuoteIdentifier(schema)}
+}
+
+// q qualifies the {schema} placeholders in query with the configured schema.
+func (p *psqlUserRepository) q(query string) string {
+	return strings.ReplaceAll(query, "{schema}", p.schema)
+}
+
+func (p *psqlUserRepository) AddUser(ctx context.Context, user domain.User) (domain.User, error) {
+	query := p.q(`INSERT INTO {schema}.t_user (login, password_hash, role) VALUES ($1, $2, $3) RETURNING id, created_at`)
+	err := p.db.QueryRowxContext(ctx, query, user.Login, user.PasswordHash, user.Role).Scan(&user.ID, &user.CreatedAt)
+	if pgerr.Is(err, pgerr.UniqueViolation) {
+		return domain.User{}, domain.WrapError(domain.UserAlreadyExist, err)
+	}
+	if err != nil {
+		return domain.User{}, pgerr.Map(err)
+	}
+	return user, nil
+}
+
+func (p *psqlUserRepository) GetUser(ctx context.Context, id int) (domain.User, error) {
+	var user domain.User
+	err := p.db.GetContext(ctx, &user, p.q("SELECT id, login, password_hash, role, created_at FROM {schema}.t_user WHERE id = $1"), id)
+	if err == sql.ErrNoRows {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.User{}, pgerr.Map(err)
+	}
+	return user, nil
+}
+
+func (p *psqlUserRepository) GetUserByLogin(ctx context.Context, login string) (domain.User, error) {
+	var user domain.User
+	err := p.db.GetContext(ctx, &user, p.q("SELECT id, login, password_hash, role, created_at FROM {schema}.t_user WHERE login = $1"), login)
+	if err == sql.ErrNoRows {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.User{}, pgerr.Map(err)
+	}
+	return user, nil
+}
+
+func (p *psqlUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	res, err := p.db.ExecContext(ctx, p.q("UPDATE {schema}.t_user SET password_hash = $2 WHERE id = $1"), id, passwordHash)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (p *psqlUserRepository) AddSession(ctx context.Context, session domain.Session) error {
+	_, err := p.db.ExecContext(ctx, p.q("INSERT INTO {schema}.t_session (id, user_id, expires_at) VALUES ($1, $2, $3)"), session.ID, session.UserID, session.ExpiresAt)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	return nil
+}
+
+func (p *psqlUserRepository) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	var session domain.Session
+	err := p.db.GetContext(ctx, &session, p.q("SELECT id, user_id, expires_at FROM {schema}.t_session WHERE id = $1"), id)
+	if err == sql.ErrNoRows {
+		return domain.Session{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.Session{}, pgerr.Map(err)
+	}
+	return session, nil
+}
+
+func (p *psqlUserRepository) RemoveSession(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, p.q("DELETE FROM {schema}.t_session WHERE id = $1"), id)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	return nil
+}
+
+func (p *psqlUserRepository) RemoveUserSessions(ctx context.Context, userID int) error {
+	_, err := p.db.ExecContext(ctx, p.q("DELETE FROM {schema}.t_session WHERE user_id = $1"), userID)
+	if err != nil {
+		return pgerr.Map(err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/validation"
+	"strings"
+	"time"
+)
+
+// dummyHash is compared against on unknown logins so that both failure paths
+// take the same time.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("spektr-pages-api"), bcrypt.DefaultCost)
+
+type UserUsecase struct {
+	userRepo       domain.UserRepository
+	sessionTTL     time.Duration
+	contextTimeout time.Duration
+}
+
+func (u UserUsecase) Register(ctx context.Context, req domain.RegisterRequest) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	req.Login = strings.TrimSpace(req.Login)
+	if err := validation.Struct(req); err != nil {
+		return domain.User{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.User{}, domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	user, err := u.userRepo.AddUser(ctx, domain.User{
+		Login:        req.Login,
+		PasswordHash: string(hash),
+		Role:         req.Role,
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (u UserUsecase) Login(ctx context.Context, req domain.LoginRequest) (domain.User, domain.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	user, err := u.userRepo.GetUserByLogin(ctx, strings.TrimSpace(req.Login))
+	if errors.Is(err, domain.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(req.Password))
+		return domain.User{}, domain.Session{}, domain.ErrUnauthorized
+	}
+	if err != nil {
+		return domain.User{}, domain.Session{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return domain.User{}, domain.Session{}, domain.ErrUnauthorized
+	}
+	session := domain.Session{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(u.sessionTTL),
+	}
+	err = u.userRepo.AddSession(ctx, session)
+	if err != nil {
+		return domain.User{}, domain.Session{}, err
+	}
+	return user, session, nil
+}
+
+func (u UserUsecase) Logout(ctx context.Context, sessionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	err := u.userRepo.RemoveSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChangePassword also ends every session of the user, including the current one.
+func (u UserUsecase) ChangePassword(ctx context.Context, userID int, req domain.ChangePasswordRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+	user, err := u.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)) != nil {
+		return domain.ErrForbidden
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.WrapError(domain.ErrInternalServerError, err)
+	}
+	err = u.userRepo.UpdatePassword(ctx, userID, string(hash))
+	if err != nil {
+		return err
+	}
+	err = u.userRepo.RemoveUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u UserUsecase) ValidateSession(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	session, err := u.userRepo.GetSession(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrUnauthorized
+	}
+	if err != nil {
+		return err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return domain.ErrUnauthorized
+	}
+	return nil
+}
+
+func NewUserUsecase(repo domain.UserRepository, sessionTTL time.Duration, timeout time.Duration) domain.UserUsecase {
+	return &UserUsecase{
+		userRepo:       repo,
+		sessionTTL:     sessionTTL,
+		contextTimeout: timeout,
+	}
+}
@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"spektr-pages-api/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryUserRepository keeps users and sessions in maps.
+type memoryUserRepository struct {
+	users    map[int]domain.User
+	sessions map[string]domain.Session
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: map[int]domain.User{}, sessions: map[string]domain.Session{}}
+}
+
+func (r *memoryUserRepository) AddUser(ctx context.Context, user domain.User) (domain.User, error) {
+	user.ID = len(r.users) + 1
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *memoryUserRepository) GetUser(ctx context.Context, id int) (domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *memoryUserRepository) GetUserByLogin(ctx context.Context, login string) (domain.User, error) {
+	for _, user := range r.users {
+		if user.Login == login {
+			return user, nil
+		}
+	}
+	return domain.User{}, domain.ErrNotFound
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	user := r.users[id]
+	user.PasswordHash = passwordHash
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) AddSession(ctx context.Context, session domain.Session) error {
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *memoryUserRepository) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return domain.Session{}, domain.ErrNotFound
+	}
+	return session, nil
+}
+
+func (r *memoryUserRepository) RemoveSession(ctx context.Context, id string) error {
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *memoryUserRepository) RemoveUserSessions(ctx context.Context, userID int) error {
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func newTestUsecase(t *testing.T) (domain.UserUsecase, *memoryUserRepository, domain.User) {
+	t.Helper()
+	repo := newMemoryUserRepository()
+	u := NewUserUsecase(repo, time.Hour, time.Second)
+	user, err := u.Register(context.Background(), domain.RegisterRequest{
+		Login:    " editor ",
+		Password: "correct horse",
+		Role:     domain.RoleEditor,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, repo, user
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	u, _, user := newTestUsecase(t)
+
+	got, session, err := u.Login(ctx, domain.LoginRequest{Login: "editor", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if got.ID != user.ID || session.UserID != user.ID {
+		t.Errorf("Login() = user %d, session of %d; want %d", got.ID, session.UserID, user.ID)
+	}
+	if err := u.ValidateSession(ctx, session.ID); err != nil {
+		t.Errorf("ValidateSession() of a new session = %v", err)
+	}
+
+	for _, req := range []domain.LoginRequest{
+		{Login: "editor", Password: "wrong horse"},
+		{Login: "nobody", Password: "correct horse"},
+	} {
+		if _, _, err := u.Login(ctx, req); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("Login(%s/%s) error = %v, want %v", req.Login, req.Password, err, domain.ErrUnauthorized)
+		}
+	}
+}
+
+func TestRegisterRejectsLongPasswords(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	_, err := u.Register(context.Background(), domain.RegisterRequest{
+		Login:    "viewer",
+		Password: strings.Repeat("ж", 37), // 74 bytes, over bcrypt's 72
+		Role:     domain.RoleViewer,
+	})
+	var verr *domain.ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("Register() error = %v, want a validation error", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	u, _, user := newTestUsecase(t)
+	_, first, err := u.Login(ctx, domain.LoginRequest{Login: "editor", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second, err := u.Login(ctx, domain.LoginRequest{Login: "editor", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = u.ChangePassword(ctx, user.ID, domain.ChangePasswordRequest{OldPassword: "wrong horse", NewPassword: "battery staple"})
+	if !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("ChangePassword() with a wrong password error = %v, want %v", err, domain.ErrForbidden)
+	}
+	if err := u.ValidateSession(ctx, first.ID); err != nil {
+		t.Errorf("a failed ChangePassword ended a session: %v", err)
+	}
+
+	err = u.ChangePassword(ctx, user.ID, domain.ChangePasswordRequest{OldPassword: "correct horse", NewPassword: "battery staple"})
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	for _, session := range []domain.Session{first, second} {
+		if err := u.ValidateSession(ctx, session.ID); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("ValidateSession() after ChangePassword = %v, want %v", err, domain.ErrUnauthorized)
+		}
+	}
+	if _, _, err := u.Login(ctx, domain.LoginRequest{Login: "editor", Password: "correct horse"}); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("Login() with the old password error = %v, want %v", err, domain.ErrUnauthorized)
+	}
+	if _, _, err := u.Login(ctx, domain.LoginRequest{Login: "editor", Password: "battery staple"}); err != nil {
+		t.Errorf("Login() with the new password error = %v", err)
+	}
+}
+
+func TestValidateSession(t *testing.T) {
+	ctx := context.Background()
+	u, repo, user := newTestUsecase(t)
+	repo.sessions["expired"] = domain.Session{ID: "expired", UserID: user.ID, ExpiresAt: time.Now().Add(-time.Second)}
+
+	for _, id := range []string{"expired", "unknown"} {
+		if err := u.ValidateSession(ctx, id); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("ValidateSession(%s) = %v, want %v", id, err, domain.ErrUnauthorized)
+		}
+	}
+
+	_, session, err := u.Login(ctx, domain.LoginRequest{Login: "editor", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Logout(ctx, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.ValidateSession(ctx, session.ID); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("ValidateSession() after Logout = %v, want %v", err, domain.ErrUnauthorized)
+	}
+}
@@ -0,0 +1,115 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"spektr-pages-api/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of the date rule when the tag gives none.
+const dateLayout = "2006-01-02"
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Report fields by their JSON names, which is what clients send.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	v.RegisterValidation("date", validateDate)
+	v.RegisterValidation("maxbytes", validateMaxBytes)
+	return v
+}
+
+// validateDate checks a string against the layout given as the rule's
+// parameter, e.g. `validate:"date=2006-01-02"`.
+func validateDate(fl validator.FieldLevel) bool {
+	layout := fl.Param()
+	if layout == "" {
+		layout = dateLayout
+	}
+	_, err := time.Parse(layout, fl.Field().String())
+	return err == nil
+}
+
+// validateMaxBytes limits the length of a string in bytes rather than in
+// characters as max does, e.g. for bcrypt, which takes at most 72 bytes.
+func validateMaxBytes(fl validator.FieldLevel) bool {
+	n, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		panic(fmt.Sprintf("validation: invalid maxbytes parameter %q", fl.Param()))
+	}
+	return len(fl.Field().String()) <= n
+}
+
+// Struct checks the validate tags of s and returns a
+// *domain.ValidationError listing every field that fails.
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return domain.ErrBadParamInput
+	}
+	verr := &domain.ValidationError{}
+	for _, fe := range fieldErrs {
+		verr.Fields = append(verr.Fields, domain.FieldError{
+			Field:   fieldPath(fe.Namespace()),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return verr
+}
+
+// fieldPath drops the struct name from a namespace such as "Tariff.title".
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "min":
+		return fmt.Sprintf("must be at least %s long", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s long", fe.Param())
+	case "maxbytes":
+		return fmt.Sprintf("must be at most %s bytes long", fe.Param())
+	case "excluded_with":
+		return "cannot be set together with the value it clears"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "date":
+		layout := fe.Param()
+		if layout == "" {
+			layout = dateLayout
+		}
+		return "must be a date formatted as " + layout
+	default:
+		return "is invalid"
+	}
+}
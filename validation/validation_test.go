@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"spektr-pages-api/domain"
+	"spektr-pages-api/middleware"
+	"strings"
+	"testing"
+)
+
+type rules struct {
+	Day      string `json:"day" validate:"omitempty,date"`
+	Month    string `json:"month" validate:"omitempty,date=2006-01"`
+	Password string `json:"password" validate:"omitempty,maxbytes=8"`
+	Items    []int  `json:"items" validate:"dive,gt=0"`
+	Hidden   string `json:"-" validate:"omitempty,max=1"`
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		in   rules
+		want []domain.FieldError // nil when valid
+	}{
+		{
+			name: "valid",
+			in:   rules{Day: "2024-02-29", Month: "2024-02", Password: "12345678", Items: []int{1}},
+		},
+		{
+			name: "date in the default layout",
+			in:   rules{Day: "29.02.2024"},
+			want: []domain.FieldError{{Field: "day", Rule: "date", Message: "must be a date formatted as 2006-01-02"}},
+		},
+		{
+			name: "date that does not exist",
+			in:   rules{Day: "2023-02-29"},
+			want: []domain.FieldError{{Field: "day", Rule: "date", Message: "must be a date formatted as 2006-01-02"}},
+		},
+		{
+			name: "date in the layout of the tag",
+			in:   rules{Month: "2024-02-01"},
+			want: []domain.FieldError{{Field: "month", Rule: "date", Message: "must be a date formatted as 2006-01"}},
+		},
+		{
+			name: "maxbytes counts bytes, not characters",
+			in:   rules{Password: "пароль"}, // 6 characters, 12 bytes
+			want: []domain.FieldError{{Field: "password", Rule: "maxbytes", Message: "must be at most 8 bytes long"}},
+		},
+		{
+			name: "every failing field is listed by its JSON path",
+			in:   rules{Day: "x", Items: []int{1, 0}, Hidden: "xx"},
+			want: []domain.FieldError{
+				{Field: "day", Rule: "date", Message: "must be a date formatted as 2006-01-02"},
+				{Field: "items[1]", Rule: "gt", Message: "must be greater than 0"},
+				{Field: "Hidden", Rule: "max", Message: "must be at most 1 long"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Struct(tt.in)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Struct() error = %v", err)
+				}
+				return
+			}
+			var verr *domain.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Struct() error = %v, want a *domain.ValidationError", err)
+			}
+			if len(verr.Fields) != len(tt.want) {
+				t.Fatalf("Struct() fields = %+v, want %+v", verr.Fields, tt.want)
+			}
+			for i := range tt.want {
+				if verr.Fields[i] != tt.want[i] {
+					t.Errorf("field %d = %+v, want %+v", i, verr.Fields[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStructNotAStruct(t *testing.T) {
+	if err := Struct("text"); !errors.Is(err, domain.ErrBadParamInput) {
+		t.Errorf("Struct(string) error = %v, want %v", err, domain.ErrBadParamInput)
+	}
+}
+
+func TestMaxBytesBadParameter(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a maxbytes rule without a number did not panic")
+		}
+	}()
+	Struct(struct {
+		S string `validate:"maxbytes=many"`
+	}{})
+}
+
+// TestFieldErrorPayload checks the body clients get for a validation error.
+func TestFieldErrorPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	middleware.RenderError(c, Struct(rules{Day: "x", Password: strings.Repeat("x", 9)}))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"error": "validation failed: day must be a date formatted as 2006-01-02, password must be at most 8 bytes long",
+		"code":  string(domain.CodeValidation),
+		"details": []interface{}{
+			map[string]interface{}{"field": "day", "rule": "date", "message": "must be a date formatted as 2006-01-02"},
+			map[string]interface{}{"field": "password", "rule": "maxbytes", "message": "must be at most 8 bytes long"},
+		},
+	}
+	got, _ := json.Marshal(body)
+	wantJSON, _ := json.Marshal(want)
+	if string(got) != string(wantJSON) {
+		t.Errorf("body = %s, want %s", got, wantJSON)
+	}
+}